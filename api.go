@@ -2,11 +2,8 @@ package ollama
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
 )
 
 // Generate generates a response from a prompt
@@ -15,6 +12,10 @@ func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*GenerateR
 	generateReq := *req
 	generateReq.Stream = BoolPtr(false)
 
+	if err := c.checkGenerate(ctx, &generateReq); err != nil {
+		return nil, err
+	}
+
 	resp, err := c.doRequest(ctx, "POST", "/api/generate", &generateReq)
 	if err != nil {
 		return nil, err
@@ -49,17 +50,33 @@ func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (<-ch
 		streamReq := *req
 		streamReq.Stream = BoolPtr(true)
 
+		if err := c.checkGenerate(ctx, &streamReq); err != nil {
+			errorChan <- err
+			return
+		}
+
 		resp, err := c.doRequest(ctx, "POST", "/api/generate", &streamReq)
 		if err != nil {
 			errorChan <- err
 			return
 		}
 
-		dataChan, errChan := c.parseStreamResponse(resp)
+		dataChan, errChan, stop := c.parseStreamResponse(ctx, resp)
+		defer stop()
 		for {
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					// errChan is closed first, so an error is already there
+					if errChan != nil {
+						if err := <-errChan; err != nil {
+							errorChan <- err
+						}
+					}
+					return
+				}
+				if err := streamError(data); err != nil {
+					errorChan <- err
 					return
 				}
 				var genResp GenerateResponse
@@ -77,14 +94,22 @@ func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest) (<-ch
 					}
 				}
 
-				responseChan <- &genResp
+				select {
+				case responseChan <- &genResp:
+				case <-ctx.Done():
+					errorChan <- ctx.Err()
+					return
+				}
 				if genResp.Done {
 					return
 				}
-			case err := <-errChan:
-				if err != nil {
-					errorChan <- err
+			case err, ok := <-errChan:
+				if !ok {
+					// Keep reading dataChan until every buffered frame is delivered
+					errChan = nil
+					continue
 				}
+				errorChan <- err
 				return
 			case <-ctx.Done():
 				errorChan <- ctx.Err()
@@ -102,6 +127,10 @@ func (c *Client) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, err
 	chatReq := *req
 	chatReq.Stream = BoolPtr(false)
 
+	if err := c.checkChat(ctx, &chatReq); err != nil {
+		return nil, err
+	}
+
 	resp, err := c.doRequest(ctx, "POST", "/api/chat", &chatReq)
 	if err != nil {
 		return nil, err
@@ -136,17 +165,33 @@ func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (<-chan *Chat
 		streamReq := *req
 		streamReq.Stream = BoolPtr(true)
 
+		if err := c.checkChat(ctx, &streamReq); err != nil {
+			errorChan <- err
+			return
+		}
+
 		resp, err := c.doRequest(ctx, "POST", "/api/chat", &streamReq)
 		if err != nil {
 			errorChan <- err
 			return
 		}
 
-		dataChan, errChan := c.parseStreamResponse(resp)
+		dataChan, errChan, stop := c.parseStreamResponse(ctx, resp)
+		defer stop()
 		for {
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					// errChan is closed first, so an error is already there
+					if errChan != nil {
+						if err := <-errChan; err != nil {
+							errorChan <- err
+						}
+					}
+					return
+				}
+				if err := streamError(data); err != nil {
+					errorChan <- err
 					return
 				}
 				var chatResp ChatResponse
@@ -164,14 +209,22 @@ func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (<-chan *Chat
 					}
 				}
 
-				responseChan <- &chatResp
+				select {
+				case responseChan <- &chatResp:
+				case <-ctx.Done():
+					errorChan <- ctx.Err()
+					return
+				}
 				if chatResp.Done {
 					return
 				}
-			case err := <-errChan:
-				if err != nil {
-					errorChan <- err
+			case err, ok := <-errChan:
+				if !ok {
+					// Keep reading dataChan until every buffered frame is delivered
+					errChan = nil
+					continue
 				}
+				errorChan <- err
 				return
 			case <-ctx.Done():
 				errorChan <- ctx.Err()
@@ -183,8 +236,14 @@ func (c *Client) ChatStream(ctx context.Context, req *ChatRequest) (<-chan *Chat
 	return responseChan, errorChan
 }
 
-// Embed creates embeddings for the given input
+// Embed creates embeddings for the given input.
+// When req.Dimensions is set and the server returns longer vectors (older
+// servers ignore the field), the embeddings are truncated and re-normalised
+// on the client side.
 func (c *Client) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	if req.Dimensions < 0 {
+		return nil, fmt.Errorf("invalid embedding dimensions: %d", req.Dimensions)
+	}
 	resp, err := c.doRequest(ctx, "POST", "/api/embed", req)
 	if err != nil {
 		return nil, err
@@ -195,6 +254,12 @@ func (c *Client) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse,
 		return nil, fmt.Errorf("failed to parse embed response: %w", err)
 	}
 
+	if req.Dimensions > 0 {
+		if err := truncateEmbeddings(result.Embeddings, req.Dimensions); err != nil {
+			return nil, err
+		}
+	}
+
 	return &result, nil
 }
 
@@ -276,11 +341,18 @@ func (c *Client) PullStream(ctx context.Context, req *PullRequest) (<-chan *Prog
 			return
 		}
 
-		dataChan, errChan := c.parseStreamResponse(resp)
+		dataChan, errChan, stop := c.parseStreamResponse(ctx, resp)
+		defer stop()
 		for {
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					// errChan is closed first, so an error is already there
+					if errChan != nil {
+						if err := <-errChan; err != nil {
+							errorChan <- err
+						}
+					}
 					return
 				}
 				var progResp ProgressResponse
@@ -288,11 +360,19 @@ func (c *Client) PullStream(ctx context.Context, req *PullRequest) (<-chan *Prog
 					errorChan <- fmt.Errorf("failed to parse streaming response: %w", err)
 					return
 				}
-				responseChan <- &progResp
-			case err := <-errChan:
-				if err != nil {
-					errorChan <- err
+				select {
+				case responseChan <- &progResp:
+				case <-ctx.Done():
+					errorChan <- ctx.Err()
+					return
+				}
+			case err, ok := <-errChan:
+				if !ok {
+					// Keep reading dataChan until every buffered frame is delivered
+					errChan = nil
+					continue
 				}
+				errorChan <- err
 				return
 			case <-ctx.Done():
 				errorChan <- ctx.Err()
@@ -337,11 +417,18 @@ func (c *Client) PushStream(ctx context.Context, req *PushRequest) (<-chan *Prog
 			return
 		}
 
-		dataChan, errChan := c.parseStreamResponse(resp)
+		dataChan, errChan, stop := c.parseStreamResponse(ctx, resp)
+		defer stop()
 		for {
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					// errChan is closed first, so an error is already there
+					if errChan != nil {
+						if err := <-errChan; err != nil {
+							errorChan <- err
+						}
+					}
 					return
 				}
 				var progResp ProgressResponse
@@ -349,11 +436,19 @@ func (c *Client) PushStream(ctx context.Context, req *PushRequest) (<-chan *Prog
 					errorChan <- fmt.Errorf("failed to parse streaming response: %w", err)
 					return
 				}
-				responseChan <- &progResp
-			case err := <-errChan:
-				if err != nil {
-					errorChan <- err
+				select {
+				case responseChan <- &progResp:
+				case <-ctx.Done():
+					errorChan <- ctx.Err()
+					return
+				}
+			case err, ok := <-errChan:
+				if !ok {
+					// Keep reading dataChan until every buffered frame is delivered
+					errChan = nil
+					continue
 				}
+				errorChan <- err
 				return
 			case <-ctx.Done():
 				errorChan <- ctx.Err()
@@ -398,11 +493,18 @@ func (c *Client) CreateStream(ctx context.Context, req *CreateRequest) (<-chan *
 			return
 		}
 
-		dataChan, errChan := c.parseStreamResponse(resp)
+		dataChan, errChan, stop := c.parseStreamResponse(ctx, resp)
+		defer stop()
 		for {
 			select {
 			case data, ok := <-dataChan:
 				if !ok {
+					// errChan is closed first, so an error is already there
+					if errChan != nil {
+						if err := <-errChan; err != nil {
+							errorChan <- err
+						}
+					}
 					return
 				}
 				var progResp ProgressResponse
@@ -410,11 +512,19 @@ func (c *Client) CreateStream(ctx context.Context, req *CreateRequest) (<-chan *
 					errorChan <- fmt.Errorf("failed to parse streaming response: %w", err)
 					return
 				}
-				responseChan <- &progResp
-			case err := <-errChan:
-				if err != nil {
-					errorChan <- err
+				select {
+				case responseChan <- &progResp:
+				case <-ctx.Done():
+					errorChan <- ctx.Err()
+					return
 				}
+			case err, ok := <-errChan:
+				if !ok {
+					// Keep reading dataChan until every buffered frame is delivered
+					errChan = nil
+					continue
+				}
+				errorChan <- err
 				return
 			case <-ctx.Done():
 				errorChan <- ctx.Err()
@@ -432,6 +542,7 @@ func (c *Client) Delete(ctx context.Context, req *DeleteRequest) (*StatusRespons
 	if err != nil {
 		return nil, err
 	}
+	resp.Body.Close()
 
 	status := "success"
 	if resp.StatusCode != 200 {
@@ -447,6 +558,7 @@ func (c *Client) Copy(ctx context.Context, req *CopyRequest) (*StatusResponse, e
 	if err != nil {
 		return nil, err
 	}
+	resp.Body.Close()
 
 	status := "success"
 	if resp.StatusCode != 200 {
@@ -506,36 +618,10 @@ func StringPtr(s string) *string {
 	return &s
 }
 
-// CreateBlob uploads a file and returns its digest
+// CreateBlob uploads a file and returns its digest.
+// The upload is skipped if the server already has the blob.
 func (c *Client) CreateBlob(ctx context.Context, path string) (string, error) {
-	// Calculate SHA256 hash
-	file, err := os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to calculate hash: %w", err)
-	}
-	digest := fmt.Sprintf("sha256:%x", hash.Sum(nil))
-
-	// Reopen file for upload
-	file, err = os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to reopen file: %w", err)
-	}
-	defer file.Close()
-
-	// Upload the blob
-	resp, err := c.doRequestWithBody(ctx, "POST", fmt.Sprintf("/api/blobs/%s", digest), file)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload blob: %w", err)
-	}
-	resp.Body.Close()
-
-	return digest, nil
+	return c.CreateBlobFile(ctx, path, nil)
 }
 
 // CheckBlob checks if a blob exists on the server
@@ -0,0 +1,221 @@
+package ollama
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// defaultBlobConcurrency is the number of parallel uploads used by
+// CreateBlobs when BlobOptions.Concurrency is not set.
+const defaultBlobConcurrency = 4
+
+// BlobProgress reports the upload state of a single blob.
+type BlobProgress struct {
+	// Path is the local file being uploaded; empty for CreateBlobFrom.
+	Path      string
+	Digest    string
+	Completed int64
+	Total     int64
+	// Skipped is true when the server already had the blob.
+	Skipped bool
+}
+
+// BlobOptions configures blob uploads.
+type BlobOptions struct {
+	// Progress is called as bytes are uploaded and once when a blob is
+	// skipped because the server already has it. With CreateBlobs it may be
+	// called from several goroutines at once.
+	Progress func(BlobProgress)
+
+	// Concurrency limits parallel uploads in CreateBlobs (default 4).
+	Concurrency int
+
+	// Force uploads the blob without checking whether it already exists.
+	Force bool
+}
+
+// CreateBlobFile uploads a file and returns its digest. The file is opened
+// once: it is hashed, the server is asked whether it already has the blob
+// and only then is the file rewound and uploaded.
+func (c *Client) CreateBlobFile(ctx context.Context, path string, opts *BlobOptions) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return c.uploadBlob(ctx, file, info.Size(), path, opts)
+}
+
+// CreateBlobFrom uploads the contents of r and returns its digest. size is
+// the number of bytes r will produce, or -1 if unknown.
+//
+// If r is an io.ReadSeeker it is hashed and rewound; otherwise the data is
+// spooled to a temporary file while hashing, since the digest must be known
+// before the upload starts.
+func (c *Client) CreateBlobFrom(ctx context.Context, r io.Reader, size int64, opts *BlobOptions) (string, error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		return c.uploadBlob(ctx, rs, size, "", opts)
+	}
+
+	tmp, err := os.CreateTemp("", "ollama-blob-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read blob data: %w", err)
+	}
+	if size >= 0 && n != size {
+		return "", fmt.Errorf("blob size mismatch: expected %d bytes, read %d", size, n)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+
+	return c.uploadBlob(ctx, tmp, n, "", opts)
+}
+
+// CreateBlobs uploads several files concurrently and returns their digests
+// keyed by path. The first error cancels the remaining uploads.
+func (c *Client) CreateBlobs(ctx context.Context, paths []string, opts *BlobOptions) (map[string]string, error) {
+	concurrency := defaultBlobConcurrency
+	if opts != nil && opts.Concurrency > 0 {
+		concurrency = opts.Concurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		digests  = make(map[string]string, len(paths))
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for _, path := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			digest, err := c.CreateBlobFile(ctx, path, opts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", path, err)
+					cancel()
+				}
+				return
+			}
+			digests[path] = digest
+		}(path)
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return digests, nil
+}
+
+// uploadBlob hashes r, skips the upload if the server has the blob and
+// otherwise rewinds r and streams it with progress reporting.
+func (c *Client) uploadBlob(ctx context.Context, r io.ReadSeeker, size int64, path string, opts *BlobOptions) (string, error) {
+	if opts == nil {
+		opts = &BlobOptions{}
+	}
+
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("failed to seek blob data: %w", err)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate hash: %w", err)
+	}
+	if size >= 0 && n != size {
+		return "", fmt.Errorf("blob size mismatch: expected %d bytes, read %d", size, n)
+	}
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+
+	if !opts.Force {
+		exists, err := c.CheckBlob(ctx, digest)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			if opts.Progress != nil {
+				opts.Progress(BlobProgress{Path: path, Digest: digest, Completed: n, Total: n, Skipped: true})
+			}
+			return digest, nil
+		}
+	}
+
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind blob data: %w", err)
+	}
+
+	var body io.Reader = io.LimitReader(r, n)
+	if opts.Progress != nil {
+		body = &progressReader{
+			r:        body,
+			progress: BlobProgress{Path: path, Digest: digest, Total: n},
+			fn:       opts.Progress,
+		}
+	}
+
+	resp, err := c.doRequestWithBody(ctx, "POST", fmt.Sprintf("/api/blobs/%s", digest), body, n)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+	resp.Body.Close()
+
+	return digest, nil
+}
+
+// progressReader reports the number of bytes read through it.
+type progressReader struct {
+	r        io.Reader
+	progress BlobProgress
+	fn       func(BlobProgress)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.progress.Completed += int64(n)
+		p.fn(p.progress)
+	}
+	return n, err
+}
@@ -0,0 +1,148 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCreateBlobFromSkipsExisting(t *testing.T) {
+	var mu sync.Mutex
+	blobs := make(map[string]bool)
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		digest := strings.TrimPrefix(r.URL.Path, "/api/blobs/")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case "HEAD":
+			if !blobs[digest] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "POST":
+			data, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(data)
+			if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.ContentLength != int64(len(data)) {
+				t.Errorf("Expected Content-Length %d, got %d", len(data), r.ContentLength)
+			}
+			blobs[digest] = true
+			uploads++
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	data := bytes.Repeat([]byte("gguf"), 10000)
+
+	var last BlobProgress
+	opts := &BlobOptions{Progress: func(p BlobProgress) { last = p }}
+
+	// A plain io.Reader that cannot seek is spooled before upload
+	digest, err := client.CreateBlobFrom(context.Background(), io.MultiReader(bytes.NewReader(data)), int64(len(data)), opts)
+	if err != nil {
+		t.Fatalf("CreateBlobFrom failed: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if digest != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected digest %s", digest)
+	}
+	if last.Completed != int64(len(data)) || last.Total != int64(len(data)) || last.Skipped {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+
+	if _, err := client.CreateBlobFrom(context.Background(), bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		t.Fatalf("CreateBlobFrom failed: %v", err)
+	}
+	if !last.Skipped {
+		t.Error("Expected second upload to be skipped")
+	}
+	if uploads != 1 {
+		t.Errorf("Expected 1 upload, got %d", uploads)
+	}
+
+	if _, err := client.CreateBlobFrom(context.Background(), bytes.NewReader(data), 3, nil); err == nil {
+		t.Error("Expected size mismatch error")
+	}
+}
+
+func TestCreateBlobs(t *testing.T) {
+	var mu sync.Mutex
+	blobs := make(map[string]bool)
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		digest := strings.TrimPrefix(r.URL.Path, "/api/blobs/")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case "HEAD":
+			if !blobs[digest] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "POST":
+			data, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(data)
+			if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.ContentLength != int64(len(data)) {
+				t.Errorf("Expected Content-Length %d, got %d", len(data), r.ContentLength)
+			}
+			blobs[digest] = true
+			uploads++
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range []string{"model", "tokenizer", "config"} {
+		path := filepath.Join(dir, content)
+		if err := os.WriteFile(path, []byte(strings.Repeat(content, i+1)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	digests, err := client.CreateBlobs(context.Background(), paths, &BlobOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("CreateBlobs failed: %v", err)
+	}
+	if len(digests) != 3 || uploads != 3 {
+		t.Errorf("Expected 3 digests and uploads, got %d and %d", len(digests), uploads)
+	}
+
+	digest, err := client.CreateBlob(context.Background(), paths[0])
+	if err != nil {
+		t.Fatalf("CreateBlob failed: %v", err)
+	}
+	if digest != digests[paths[0]] || uploads != 3 {
+		t.Errorf("Expected existing blob to be reused, got %s with %d uploads", digest, uploads)
+	}
+
+	if _, err := client.CreateBlobs(context.Background(), []string{filepath.Join(dir, "missing")}, nil); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,203 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CapabilityCheckMode selects how requests are checked against the
+// capabilities a model reports.
+type CapabilityCheckMode int
+
+const (
+	// CapabilityCheckOff sends requests unchanged (the default).
+	CapabilityCheckOff CapabilityCheckMode = iota
+	// CapabilityCheckReject fails requests that use a feature the model does
+	// not support with ErrCapabilityUnsupported, before they are sent.
+	CapabilityCheckReject
+	// CapabilityCheckAdapt removes unsupported optional features (tools,
+	// images, thinking) from requests and rejects the rest.
+	CapabilityCheckAdapt
+)
+
+// ErrCapabilityUnsupported is returned when a request needs a capability the
+// model does not report.
+type ErrCapabilityUnsupported struct {
+	Model      string
+	Capability Capability
+}
+
+func (e *ErrCapabilityUnsupported) Error() string {
+	return fmt.Sprintf("ollama: model %s does not support %s", e.Model, e.Capability)
+}
+
+// WithCapabilityCheck enables pre-flight validation of Chat and Generate
+// requests (and their streaming variants) against the model's capabilities.
+// Capabilities are fetched with Show on first use of a model and cached for
+// the lifetime of the client. Embed requests are not checked: models without
+// the "embedding" capability can still serve /api/embed.
+func WithCapabilityCheck(mode CapabilityCheckMode) ClientOption {
+	return func(c *Client) {
+		c.capabilityMode = mode
+	}
+}
+
+// capabilityCache holds the capabilities of models seen by the client.
+type capabilityCache struct {
+	mu     sync.Mutex
+	models map[string][]string
+}
+
+// ModelCapabilities returns the capabilities the server reports for a model,
+// using the client's cache when available.
+func (c *Client) ModelCapabilities(ctx context.Context, model string) ([]Capability, error) {
+	key := model
+	if ref, err := ParseModelRef(model); err == nil {
+		key = ref.String()
+	}
+
+	c.capabilities.mu.Lock()
+	cached, ok := c.capabilities.models[key]
+	c.capabilities.mu.Unlock()
+
+	if !ok {
+		info, err := c.Show(ctx, &ShowRequest{Model: model})
+		if err != nil {
+			return nil, err
+		}
+		cached = info.Capabilities
+
+		c.capabilities.mu.Lock()
+		if c.capabilities.models == nil {
+			c.capabilities.models = make(map[string][]string)
+		}
+		c.capabilities.models[key] = cached
+		c.capabilities.mu.Unlock()
+	}
+
+	capabilities := make([]Capability, len(cached))
+	for i, capability := range cached {
+		capabilities[i] = Capability(capability)
+	}
+	return capabilities, nil
+}
+
+// ClearCapabilityCache forgets all cached model capabilities, e.g. after a
+// model has been re-created.
+func (c *Client) ClearCapabilityCache() {
+	c.capabilities.mu.Lock()
+	c.capabilities.models = nil
+	c.capabilities.mu.Unlock()
+}
+
+// capabilitySet loads the model's capabilities for a pre-flight check. It
+// returns nil if checks are disabled, the model cannot be inspected, or the
+// server does not report capabilities; the request then goes out unchanged
+// and the server decides.
+func (c *Client) capabilitySet(ctx context.Context, model string) map[Capability]bool {
+	if c.capabilityMode == CapabilityCheckOff {
+		return nil
+	}
+
+	capabilities, err := c.ModelCapabilities(ctx, model)
+	if err != nil || len(capabilities) == 0 {
+		return nil
+	}
+
+	set := make(map[Capability]bool, len(capabilities))
+	for _, capability := range capabilities {
+		set[capability] = true
+	}
+	return set
+}
+
+// checkChat validates (and in adapt mode rewrites) a chat request in place.
+// req must be a copy owned by the caller.
+func (c *Client) checkChat(ctx context.Context, req *ChatRequest) error {
+	set := c.capabilitySet(ctx, req.Model)
+	if set == nil {
+		return nil
+	}
+	unsupported := func(capability Capability) error {
+		return &ErrCapabilityUnsupported{Model: req.Model, Capability: capability}
+	}
+	adapt := c.capabilityMode == CapabilityCheckAdapt
+
+	if !set[CapabilityCompletion] {
+		return unsupported(CapabilityCompletion)
+	}
+
+	if len(req.Tools) > 0 && !set[CapabilityTools] {
+		if !adapt {
+			return unsupported(CapabilityTools)
+		}
+		req.Tools = nil
+	}
+
+	if req.Think != nil && *req.Think && !set[CapabilityThinking] {
+		if !adapt {
+			return unsupported(CapabilityThinking)
+		}
+		req.Think = nil
+	}
+
+	if !set[CapabilityVision] {
+		copied := false
+		for i, msg := range req.Messages {
+			if len(msg.Images) == 0 {
+				continue
+			}
+			if !adapt {
+				return unsupported(CapabilityVision)
+			}
+			// Copy the messages before the first change so the caller's
+			// slice is left untouched
+			if !copied {
+				req.Messages = append([]Message(nil), req.Messages...)
+				copied = true
+			}
+			req.Messages[i].Images = nil
+		}
+	}
+
+	return nil
+}
+
+// checkGenerate validates (and in adapt mode rewrites) a generate request in
+// place. req must be a copy owned by the caller.
+func (c *Client) checkGenerate(ctx context.Context, req *GenerateRequest) error {
+	set := c.capabilitySet(ctx, req.Model)
+	if set == nil {
+		return nil
+	}
+	unsupported := func(capability Capability) error {
+		return &ErrCapabilityUnsupported{Model: req.Model, Capability: capability}
+	}
+	adapt := c.capabilityMode == CapabilityCheckAdapt
+
+	// An empty prompt only loads or unloads the model
+	if req.Prompt != "" && !set[CapabilityCompletion] {
+		return unsupported(CapabilityCompletion)
+	}
+
+	if req.Suffix != "" && !set[CapabilityInsert] {
+		return unsupported(CapabilityInsert)
+	}
+
+	if req.Think != nil && *req.Think && !set[CapabilityThinking] {
+		if !adapt {
+			return unsupported(CapabilityThinking)
+		}
+		req.Think = nil
+	}
+
+	if len(req.Images) > 0 && !set[CapabilityVision] {
+		if !adapt {
+			return unsupported(CapabilityVision)
+		}
+		req.Images = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newCapabilityServer serves Show with fixed capabilities per model and
+// records the last chat and generate requests.
+func newCapabilityServer(t *testing.T, lastChat *ChatRequest, lastGenerate *GenerateRequest) (*httptest.Server, *int32) {
+	capabilities := map[string][]string{
+		"text":  {"completion"},
+		"full":  {"completion", "tools", "vision", "thinking", "insert"},
+		"embed": {"embedding"},
+	}
+	var shows int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			atomic.AddInt32(&shows, 1)
+			var req ShowRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			_ = json.NewEncoder(w).Encode(ShowResponse{Capabilities: capabilities[req.Model]})
+		case "/api/chat":
+			_ = json.NewDecoder(r.Body).Decode(lastChat)
+			_ = json.NewEncoder(w).Encode(ChatResponse{Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+		case "/api/generate":
+			_ = json.NewDecoder(r.Body).Decode(lastGenerate)
+			_ = json.NewEncoder(w).Encode(GenerateResponse{Response: "ok", Done: true})
+		case "/api/embed":
+			_ = json.NewEncoder(w).Encode(EmbedResponse{Embeddings: [][]float64{{1}}})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+
+	return server, &shows
+}
+
+func TestCapabilityCheckReject(t *testing.T) {
+	var lastChat ChatRequest
+	var lastGenerate GenerateRequest
+	server, shows := newCapabilityServer(t, &lastChat, &lastGenerate)
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithCapabilityCheck(CapabilityCheckReject))
+	ctx := context.Background()
+	tools := []Tool{{Type: "function", Function: &ToolFunction{Name: "weather"}}}
+
+	_, err := client.Chat(ctx, &ChatRequest{Model: "text", Tools: tools})
+	var capErr *ErrCapabilityUnsupported
+	if !errors.As(err, &capErr) || capErr.Model != "text" || capErr.Capability != CapabilityTools {
+		t.Fatalf("Expected tools to be rejected, got %v", err)
+	}
+
+	if _, err := client.Chat(ctx, &ChatRequest{Model: "full", Tools: tools, Think: BoolPtr(true)}); err != nil {
+		t.Errorf("Expected full model to accept tools, got %v", err)
+	}
+
+	_, err = client.Generate(ctx, &GenerateRequest{Model: "text", Prompt: "def f(", Suffix: "return x"})
+	if !errors.As(err, &capErr) || capErr.Capability != CapabilityInsert {
+		t.Errorf("Expected suffix to be rejected, got %v", err)
+	}
+
+	_, errChan := client.GenerateStream(ctx, &GenerateRequest{Model: "text", Prompt: "hi", Images: []Image{{Data: "aGk="}}})
+	if err := <-errChan; !errors.As(err, &capErr) || capErr.Capability != CapabilityVision {
+		t.Errorf("Expected images to be rejected, got %v", err)
+	}
+
+	// Completion models can embed too, so embed is left to the server
+	if _, err := client.Embed(ctx, &EmbedRequest{Model: "text", Input: "hi"}); err != nil {
+		t.Errorf("Expected embed to be sent unchecked, got %v", err)
+	}
+
+	_, err = client.Chat(ctx, &ChatRequest{Model: "embed", Messages: []Message{{Role: "user", Content: "hi"}}})
+	if !errors.As(err, &capErr) || capErr.Capability != CapabilityCompletion {
+		t.Errorf("Expected chat on embed model to be rejected, got %v", err)
+	}
+
+	// "text" and "text:latest" share a cache entry
+	if n := atomic.LoadInt32(shows); n != 3 {
+		t.Errorf("Expected 3 Show calls, got %d", n)
+	}
+	_, _ = client.Chat(ctx, &ChatRequest{Model: "text:latest"})
+	if n := atomic.LoadInt32(shows); n != 3 {
+		t.Errorf("Expected cached capabilities, got %d Show calls", n)
+	}
+
+	client.ClearCapabilityCache()
+	_, _ = client.Chat(ctx, &ChatRequest{Model: "text"})
+	if n := atomic.LoadInt32(shows); n != 4 {
+		t.Errorf("Expected Show after clearing cache, got %d calls", n)
+	}
+}
+
+func TestCapabilityCheckAdapt(t *testing.T) {
+	var lastChat ChatRequest
+	var lastGenerate GenerateRequest
+	server, _ := newCapabilityServer(t, &lastChat, &lastGenerate)
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithCapabilityCheck(CapabilityCheckAdapt))
+	ctx := context.Background()
+
+	messages := []Message{
+		{Role: "user", Content: "What is this?", Images: []Image{{Data: "aGk="}}},
+	}
+	req := &ChatRequest{
+		Model:    "text",
+		Messages: messages,
+		Tools:    []Tool{{Type: "function", Function: &ToolFunction{Name: "weather"}}},
+		Think:    BoolPtr(true),
+	}
+
+	if _, err := client.Chat(ctx, req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(lastChat.Tools) != 0 || lastChat.Think != nil || len(lastChat.Messages[0].Images) != 0 {
+		t.Errorf("Expected unsupported features to be removed, got %+v", lastChat)
+	}
+	if len(req.Tools) != 1 || len(messages[0].Images) != 1 {
+		t.Error("Caller's request must not be modified")
+	}
+
+	if _, err := client.Generate(ctx, &GenerateRequest{Model: "text", Prompt: "hi", Think: BoolPtr(true)}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if lastGenerate.Think != nil {
+		t.Error("Expected think to be removed")
+	}
+
+	var capErr *ErrCapabilityUnsupported
+	if _, err := client.Generate(ctx, &GenerateRequest{Model: "text", Prompt: "a", Suffix: "b"}); !errors.As(err, &capErr) {
+		t.Errorf("Expected suffix to be rejected even in adapt mode, got %v", err)
+	}
+}
+
+func TestCapabilityCheckOffByDefault(t *testing.T) {
+	var lastChat ChatRequest
+	var lastGenerate GenerateRequest
+	server, shows := newCapabilityServer(t, &lastChat, &lastGenerate)
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	tools := []Tool{{Type: "function", Function: &ToolFunction{Name: "weather"}}}
+	if _, err := client.Chat(context.Background(), &ChatRequest{Model: "text", Tools: tools}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if n := atomic.LoadInt32(shows); n != 0 {
+		t.Errorf("Expected no Show calls, got %d", n)
+	}
+}
@@ -0,0 +1,255 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitFailureThreshold = 5
+	defaultCircuitOpenTimeout      = 30 * time.Second
+	defaultCircuitHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is returned without sending the request when the circuit
+// for the request's host and model is open.
+var ErrCircuitOpen = errors.New("ollama: circuit breaker is open")
+
+// CircuitState is the state of a circuit.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and counts failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests with ErrCircuitOpen until OpenTimeout
+	// has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets HalfOpenRequests probe requests through. A
+	// successful probe closes the circuit and a failed one opens it again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreakerOptions configures WithCircuitBreaker. Zero values use the
+// defaults.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens a
+	// circuit (default 5).
+	FailureThreshold int
+	// OpenTimeout is how long a circuit stays open before letting probe
+	// requests through (default 30s).
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of concurrent probe requests allowed
+	// while half-open (default 1).
+	HalfOpenRequests int
+
+	// IsFailure reports whether an error counts as a failure. By default
+	// connection errors, 5xx statuses and error frames in streams count;
+	// client errors (4xx) and canceled requests do not.
+	IsFailure func(err error) bool
+
+	// OnStateChange is called after a circuit changes state, e.g. to raise
+	// an alert. It must not block.
+	OnStateChange func(host, model string, from, to CircuitState)
+}
+
+// WithCircuitBreaker adds a circuit breaker with one circuit per host and
+// model, so a model that keeps crashing on one server is failed fast without
+// affecting other models or servers. Requests that are not about a model,
+// such as List, share a circuit per host.
+//
+// Failures are counted when the response is complete, so a stream that ends
+// with an error frame counts as a failure. In a Pool, a request rejected by
+// an open circuit is failed over to another host.
+//
+// Example:
+//
+//	client, err := ollama.NewClient(
+//		ollama.WithCircuitBreaker(&ollama.CircuitBreakerOptions{
+//			FailureThreshold: 3,
+//			OnStateChange: func(host, model string, from, to ollama.CircuitState) {
+//				log.Printf("circuit %s %s: %s -> %s", host, model, from, to)
+//			},
+//		}),
+//	)
+func WithCircuitBreaker(opts *CircuitBreakerOptions) ClientOption {
+	var o CircuitBreakerOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = defaultCircuitFailureThreshold
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = defaultCircuitOpenTimeout
+	}
+	if o.HalfOpenRequests <= 0 {
+		o.HalfOpenRequests = defaultCircuitHalfOpenRequests
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = isCircuitFailure
+	}
+
+	return func(c *Client) {
+		c.breaker = &circuitBreaker{opts: o, circuits: make(map[circuitKey]*circuit)}
+	}
+}
+
+// isCircuitFailure is the default CircuitBreakerOptions.IsFailure.
+func isCircuitFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		// Error frames in a stream are reported with status 200
+		return respErr.StatusCode >= 500 || respErr.StatusCode == http.StatusOK
+	}
+	return true
+}
+
+type circuitKey struct {
+	host  string
+	model string
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	retryAt  time.Time
+	probes   int
+}
+
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mu       sync.Mutex
+	circuits map[circuitKey]*circuit
+}
+
+type circuitChange struct {
+	key      circuitKey
+	from, to CircuitState
+}
+
+// send runs one request through the circuit for its host and model.
+func (b *circuitBreaker) send(ctx context.Context, baseURL *url.URL, r *Request,
+	next func(context.Context, *url.URL, *Request) (*http.Response, error)) (*http.Response, error) {
+	key := circuitKey{host: baseURL.String(), model: modelKey(r.Model())}
+
+	probe, err := b.allow(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := next(ctx, baseURL, r)
+	if err != nil {
+		b.record(key, probe, err)
+		return nil, err
+	}
+
+	ObserveResponse(resp, false, func(stats ResponseStats) {
+		b.record(key, probe, stats.Err)
+	})
+	return resp, nil
+}
+
+// allow reports whether a request may be sent and whether it is a probe.
+func (b *circuitBreaker) allow(key circuitKey) (bool, error) {
+	var changes []circuitChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	if c.state == CircuitOpen && !time.Now().Before(c.retryAt) {
+		changes = append(changes, b.setState(key, c, CircuitHalfOpen))
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		return false, fmt.Errorf("%w for %s", ErrCircuitOpen, key)
+	case CircuitHalfOpen:
+		if c.probes >= b.opts.HalfOpenRequests {
+			return false, fmt.Errorf("%w for %s", ErrCircuitOpen, key)
+		}
+		c.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the circuit with the outcome of a request.
+func (b *circuitBreaker) record(key circuitKey, probe bool, err error) {
+	var changes []circuitChange
+	defer func() { b.notify(changes) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[key]
+	if probe {
+		c.probes--
+	}
+
+	switch {
+	case err == nil:
+		c.failures = 0
+		if probe && c.state == CircuitHalfOpen {
+			changes = append(changes, b.setState(key, c, CircuitClosed))
+		}
+	case b.opts.IsFailure(err):
+		c.failures++
+		if (probe && c.state == CircuitHalfOpen) ||
+			(c.state == CircuitClosed && c.failures >= b.opts.FailureThreshold) {
+			c.retryAt = time.Now().Add(b.opts.OpenTimeout)
+			changes = append(changes, b.setState(key, c, CircuitOpen))
+		}
+	}
+}
+
+// setState changes the state of c. b.mu must be held.
+func (b *circuitBreaker) setState(key circuitKey, c *circuit, state CircuitState) circuitChange {
+	change := circuitChange{key: key, from: c.state, to: state}
+	c.state = state
+	if state == CircuitClosed {
+		c.failures = 0
+	}
+	return change
+}
+
+func (b *circuitBreaker) notify(changes []circuitChange) {
+	if b.opts.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		b.opts.OnStateChange(change.key.host, change.key.model, change.from, change.to)
+	}
+}
+
+func (k circuitKey) String() string {
+	if k.model == "" {
+		return k.host
+	}
+	return k.host + " " + k.model
+}
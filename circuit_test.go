@@ -0,0 +1,157 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "broken" {
+			atomic.AddInt32(&hits, 1)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":"llama runner process has terminated"}`))
+				return
+			}
+		}
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model not found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: req.Model, Message: Message{Content: "ok"}, Done: true})
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	client, _ := NewClient(WithHost(server.URL), WithCircuitBreaker(&CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		OnStateChange: func(host, model string, from, to CircuitState) {
+			mu.Lock()
+			changes = append(changes, fmt.Sprintf("%s %s->%s", model, from, to))
+			mu.Unlock()
+		},
+	}))
+	ctx := context.Background()
+
+	// Client errors do not count
+	for i := 0; i < 3; i++ {
+		if _, err := client.Chat(ctx, chatRequest("missing")); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("Expected 4xx responses not to open the circuit")
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat(ctx, chatRequest("broken")); err == nil {
+			t.Fatal("Expected a server error")
+		}
+	}
+	_, err := client.Chat(ctx, chatRequest("broken"))
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected the open circuit to fail fast, server saw %d requests", n)
+	}
+
+	// Other models on the same host are unaffected
+	if _, err := client.Chat(ctx, chatRequest("llama3")); err != nil {
+		t.Errorf("Expected llama3 to work, got %v", err)
+	}
+
+	// A failed probe opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Chat(ctx, chatRequest("broken")); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the probe to reach the server, got %v", err)
+	}
+	if _, err := client.Chat(ctx, chatRequest("broken")); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to reopen, got %v", err)
+	}
+
+	// A successful probe closes it
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Chat(ctx, chatRequest("broken")); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+
+	want := []string{
+		"broken:latest closed->open",
+		"broken:latest open->half-open",
+		"broken:latest half-open->open",
+		"broken:latest open->half-open",
+		"broken:latest half-open->closed",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Unexpected state changes:\n got %v\nwant %v", changes, want)
+	}
+}
+
+func TestCircuitBreakerStreamErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"model":"llama3","message":{"content":"Hi"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"error":"model runner has unexpectedly stopped"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 1}))
+
+	responseChan, errorChan := client.ChatStream(context.Background(), chatRequest("llama3"))
+	for range responseChan {
+	}
+	if err := <-errorChan; err == nil {
+		t.Fatal("Expected a stream error")
+	}
+
+	_, err := client.Chat(context.Background(), chatRequest("llama3"))
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the error frame to open the circuit, got %v", err)
+	}
+}
+
+func TestPoolCircuitFailover(t *testing.T) {
+	var crashes int32
+	crashing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&crashes, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":"overloaded"}`))
+	}))
+	defer crashing.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+	}))
+	defer good.Close()
+
+	pool, _ := NewPool([]string{crashing.URL, good.URL}, &PoolOptions{MaxFailures: 10},
+		WithCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour}))
+
+	for i := 0; i < 4; i++ {
+		if _, err := pool.Chat(context.Background(), chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat %d failed: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&crashes); n != 1 {
+		t.Errorf("Expected one request to the crashing host, got %d", n)
+	}
+	if status := pool.Status(); status[0].Failures != 1 {
+		t.Errorf("Expected open circuits not to count as host failures, got %+v", status[0])
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -25,6 +26,18 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    *url.URL
 	headers    map[string]string
+
+	ensureMu sync.Mutex
+	ensures  map[string]*ensureCall
+
+	capabilityMode CapabilityCheckMode
+	capabilities   capabilityCache
+
+	middleware []Middleware
+	// transport replaces send as the innermost RoundTripFunc, e.g. to route
+	// requests across the hosts of a Pool
+	transport RoundTripFunc
+	breaker   *circuitBreaker
 }
 
 // ClientOption defines a function type for configuring the client.
@@ -99,67 +112,92 @@ func WithHeaders(headers map[string]string) ClientOption {
 	}
 }
 
-// doRequest performs an HTTP request
+// doRequest performs an HTTP request with a JSON body
 func (c *Client) doRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
-	var bodyReader io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(jsonBody)
+	req := &Request{
+		Method:        method,
+		Endpoint:      endpoint,
+		Body:          body,
+		ContentLength: -1,
+		Header:        make(http.Header),
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
 	}
 
-	url := c.baseURL.String() + endpoint
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	return c.roundTrip(ctx, req)
+}
+
+// doRequestWithBody performs an HTTP request with a body reader (for file uploads).
+// A non-negative size is sent as the Content-Length.
+func (c *Client) doRequestWithBody(ctx context.Context, method, endpoint string, body io.Reader, size int64) (*http.Response, error) {
+	req := &Request{
+		Method:        method,
+		Endpoint:      endpoint,
+		RawBody:       body,
+		ContentLength: size,
+		Header:        make(http.Header),
 	}
 
+	// Set headers, but exclude Content-Type for file uploads to let HTTP set it
 	for key, value := range c.headers {
-		req.Header.Set(key, value)
+		if key != "Content-Type" {
+			req.Header.Set(key, value)
+		}
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	return c.roundTrip(ctx, req)
+}
+
+// roundTrip runs a request through the client's middleware chain.
+func (c *Client) roundTrip(ctx context.Context, req *Request) (*http.Response, error) {
+	next := c.send
+	if c.transport != nil {
+		next = c.transport
 	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		next = c.middleware[i](next)
+	}
+	return next(ctx, req)
+}
 
-	if resp.StatusCode >= 400 {
-		defer resp.Body.Close()
-		bodyBytes, _ := io.ReadAll(resp.Body)
+// send is the innermost RoundTripFunc: it encodes the request, sends it and
+// turns error statuses into a *ResponseError.
+func (c *Client) send(ctx context.Context, r *Request) (*http.Response, error) {
+	return c.sendTo(ctx, c.baseURL, r)
+}
 
-		// Try to parse as JSON error first, like Python version
-		var errorResp map[string]interface{}
-		errorMsg := string(bodyBytes)
-		if json.Unmarshal(bodyBytes, &errorResp) == nil {
-			if errStr, ok := errorResp["error"].(string); ok && errStr != "" {
-				errorMsg = errStr
-			}
-		}
+// sendTo sends a request to the server at baseURL, through the circuit
+// breaker when one is configured.
+func (c *Client) sendTo(ctx context.Context, baseURL *url.URL, r *Request) (*http.Response, error) {
+	if c.breaker != nil {
+		return c.breaker.send(ctx, baseURL, r, c.sendHTTP)
+	}
+	return c.sendHTTP(ctx, baseURL, r)
+}
 
-		return nil, &ResponseError{
-			StatusCode: resp.StatusCode,
-			Message:    errorMsg,
+// sendHTTP performs the HTTP exchange for a request.
+func (c *Client) sendHTTP(ctx context.Context, baseURL *url.URL, r *Request) (*http.Response, error) {
+	bodyReader := r.RawBody
+	contentLength := r.ContentLength
+	if r.Body != nil {
+		jsonBody, err := json.Marshal(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+		bodyReader = bytes.NewReader(jsonBody)
+		contentLength = int64(len(jsonBody))
 	}
 
-	return resp, nil
-}
-
-// doRequestWithBody performs an HTTP request with a body reader (for file uploads)
-func (c *Client) doRequestWithBody(ctx context.Context, method, endpoint string, body io.Reader) (*http.Response, error) {
-	url := c.baseURL.String() + endpoint
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	req, err := http.NewRequestWithContext(ctx, r.Method, baseURL.String()+r.Endpoint, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
-	// Set headers, but exclude Content-Type for file uploads to let HTTP set it
-	for key, value := range c.headers {
-		if key != "Content-Type" {
-			req.Header.Set(key, value)
-		}
+	if contentLength >= 0 && bodyReader != nil {
+		req.ContentLength = contentLength
+	}
+	for key, values := range r.Header {
+		req.Header[key] = append([]string(nil), values...)
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -195,10 +233,19 @@ func (c *Client) parseJSONResponse(resp *http.Response, target interface{}) erro
 	return json.NewDecoder(resp.Body).Decode(target)
 }
 
-// parseStreamResponse handles streaming responses
-func (c *Client) parseStreamResponse(resp *http.Response) (<-chan []byte, <-chan error) {
+// parseStreamResponse decodes the frames of a streaming response. The body
+// is closed once it has been read, when ctx is done, or after stop has been
+// called by a consumer that gives up early, so that whatever tracks the
+// request (a scheduler slot, a pool host's load, a circuit breaker probe) is
+// released. Only the decoding goroutine touches the body: a read blocked on a
+// server that sends nothing is interrupted by the transport when ctx, which
+// the request was sent with, is done.
+func (c *Client) parseStreamResponse(ctx context.Context, resp *http.Response) (<-chan []byte, <-chan error, func()) {
 	dataChan := make(chan []byte, 100)
 	errChan := make(chan error, 1)
+	stopped := make(chan struct{})
+	var once sync.Once
+	stop := func() { once.Do(func() { close(stopped) }) }
 
 	go func() {
 		defer resp.Body.Close()
@@ -209,14 +256,36 @@ func (c *Client) parseStreamResponse(resp *http.Response) (<-chan []byte, <-chan
 		for decoder.More() {
 			var rawMessage json.RawMessage
 			if err := decoder.Decode(&rawMessage); err != nil {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
 				if err != io.EOF {
 					errChan <- fmt.Errorf("failed to decode response: %w", err)
 				}
 				return
 			}
-			dataChan <- rawMessage
+			select {
+			case dataChan <- rawMessage:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			case <-stopped:
+				return
+			}
 		}
 	}()
 
-	return dataChan, errChan
+	return dataChan, errChan, stop
+}
+
+// streamError returns the error carried by an error frame, which the server
+// sends when generation fails after the response headers were written.
+func streamError(data []byte) error {
+	var frame struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &frame) != nil || frame.Error == "" {
+		return nil
+	}
+	return &ResponseError{StatusCode: http.StatusOK, Message: frame.Error}
 }
@@ -748,3 +748,30 @@ func TestNonJSONErrorResponse(t *testing.T) {
 		t.Errorf("Expected ResponseError, got %T", err)
 	}
 }
+
+func TestStreamErrorFrame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte(`{"model":"test-model","message":{"role":"assistant","content":"Hi"},"done":false}` + "\n"))
+		_, _ = w.Write([]byte(`{"error":"model runner has unexpectedly stopped"}` + "\n"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	respCh, errCh := client.ChatStream(context.Background(), &ChatRequest{
+		Model:    "test-model",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	})
+	for range respCh {
+	}
+
+	err := <-errCh
+	respErr, ok := err.(*ResponseError)
+	if !ok {
+		t.Fatalf("Expected ResponseError, got %T (%v)", err, err)
+	}
+	if respErr.Message != "model runner has unexpectedly stopped" {
+		t.Errorf("Expected the error frame message, got '%s'", respErr.Message)
+	}
+}
@@ -0,0 +1,184 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// modelFileNames are the non-weight files the server needs to import a model
+// or adapter from safetensors.
+var modelFileNames = map[string]bool{
+	"config.json":             true,
+	"generation_config.json":  true,
+	"tokenizer.json":          true,
+	"tokenizer_config.json":   true,
+	"tokenizer.model":         true,
+	"special_tokens_map.json": true,
+	"added_tokens.json":       true,
+	"vocab.json":              true,
+	"merges.txt":              true,
+	"adapter_config.json":     true,
+}
+
+// CreateDirectoryOptions configures CreateFromDirectory.
+type CreateDirectoryOptions struct {
+	// From is the base model. It is required when the directory holds a
+	// LoRA adapter and ignored otherwise.
+	From string
+
+	// Adapter forces the directory to be treated as a LoRA adapter. It is
+	// detected automatically when adapter_config.json is present.
+	Adapter bool
+
+	Quantize   string
+	Template   string
+	System     string
+	License    interface{} // string or []string
+	Parameters *Options
+	Messages   []Message
+
+	// Concurrency limits parallel blob uploads (default 4).
+	Concurrency int
+
+	// BlobProgress is called while files are hashed and uploaded.
+	BlobProgress func(BlobProgress)
+
+	// Progress is called for every progress frame from /api/create.
+	Progress func(*ProgressResponse)
+}
+
+// CreateFromDirectory creates a model from a local directory of safetensors
+// or GGUF files. It selects the weight, tokenizer and config files, uploads
+// the blobs the server does not already have, and creates the model with the
+// resulting digests in Files (or Adapters for a LoRA adapter directory).
+//
+// Example:
+//
+//	status, err := client.CreateFromDirectory(ctx, "my-model", "./Llama-3.2-1B", &ollama.CreateDirectoryOptions{
+//		Quantize: "q4_K_M",
+//		Progress: func(p *ollama.ProgressResponse) { fmt.Println(p.Status) },
+//	})
+func (c *Client) CreateFromDirectory(ctx context.Context, name, dir string, opts *CreateDirectoryOptions) (*StatusResponse, error) {
+	if opts == nil {
+		opts = &CreateDirectoryOptions{}
+	}
+
+	files, adapter, err := selectModelFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	adapter = adapter || opts.Adapter
+	if adapter && opts.From == "" {
+		return nil, fmt.Errorf("%s contains an adapter; a base model must be set with From", dir)
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(dir, file)
+	}
+
+	digests, err := c.CreateBlobs(ctx, paths, &BlobOptions{
+		Concurrency: opts.Concurrency,
+		Progress:    opts.BlobProgress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[string]string, len(files))
+	for i, file := range files {
+		blobs[file] = digests[paths[i]]
+	}
+
+	req := &CreateRequest{
+		Model:      name,
+		Quantize:   opts.Quantize,
+		Template:   opts.Template,
+		System:     opts.System,
+		License:    opts.License,
+		Parameters: opts.Parameters,
+		Messages:   opts.Messages,
+	}
+	if adapter {
+		req.From = opts.From
+		req.Adapters = blobs
+	} else {
+		req.Files = blobs
+	}
+
+	progressChan, errorChan := c.CreateStream(ctx, req)
+
+	status := &StatusResponse{}
+	for progress := range progressChan {
+		if progress.Error != "" {
+			for range progressChan {
+			}
+			return nil, fmt.Errorf("failed to create %s: %s", name, progress.Error)
+		}
+		if progress.Status != "" {
+			status.Status = progress.Status
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+	if err := <-errorChan; err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// selectModelFiles returns the names of the files in dir that make up a
+// model and whether the directory holds a LoRA adapter. Only the top level of
+// dir is considered. A GGUF file takes precedence over safetensors, matching
+// the server's importer, and there must be at most one.
+func selectModelFiles(dir string) ([]string, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, fmt.Errorf("model directory %s does not exist", dir)
+		}
+		return nil, false, fmt.Errorf("failed to read model directory: %w", err)
+	}
+
+	var ggufs, safetensors, extra []string
+	adapter := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(name, ".gguf"):
+			ggufs = append(ggufs, name)
+		case strings.HasSuffix(name, ".safetensors"):
+			safetensors = append(safetensors, name)
+		case modelFileNames[name]:
+			extra = append(extra, name)
+		}
+		if name == "adapter_config.json" {
+			adapter = true
+		}
+	}
+
+	var files []string
+	switch {
+	case len(ggufs) > 1:
+		return nil, false, fmt.Errorf("%s contains %d .gguf files; expected one", dir, len(ggufs))
+	case len(ggufs) == 1:
+		files = ggufs
+	case len(safetensors) > 0:
+		files = append(safetensors, extra...)
+	default:
+		return nil, false, fmt.Errorf("no .gguf or .safetensors files found in %s", dir)
+	}
+
+	sort.Strings(files)
+	return files, adapter, nil
+}
@@ -0,0 +1,213 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCreateFromDirectorySafetensors(t *testing.T) {
+	var mu sync.Mutex
+	blobs := make(map[string]bool)
+	uploads := 0
+	var requests []CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			digest := strings.TrimPrefix(r.URL.Path, "/api/blobs/")
+			if r.Method == "HEAD" {
+				if !blobs[digest] {
+					w.WriteHeader(http.StatusNotFound)
+				}
+				return
+			}
+			blobs[digest] = true
+			uploads++
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/api/create":
+			var req CreateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			requests = append(requests, req)
+
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "converting model"})
+			_ = enc.Encode(ProgressResponse{Status: "writing manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"model-00001-of-00002.safetensors": "weights-1",
+		"model-00002-of-00002.safetensors": "weights-2",
+		"config.json":                      "{}",
+		"tokenizer.json":                   "{\"tok\":1}",
+		"README.md":                        "ignored",
+		".git/config":                      "ignored",
+		"original/model.safetensors":       "ignored",
+	})
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	var statuses []string
+	status, err := client.CreateFromDirectory(context.Background(), "my-model", dir, &CreateDirectoryOptions{
+		Quantize: "q4_K_M",
+		Progress: func(p *ProgressResponse) { statuses = append(statuses, p.Status) },
+	})
+	if err != nil {
+		t.Fatalf("CreateFromDirectory failed: %v", err)
+	}
+	if status.Status != "success" || len(statuses) != 3 {
+		t.Errorf("Unexpected status %q and progress %v", status.Status, statuses)
+	}
+
+	if uploads != 4 {
+		t.Errorf("Expected 4 uploaded blobs, got %d", uploads)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 create request, got %d", len(requests))
+	}
+	req := requests[0]
+	if req.Model != "my-model" || req.Quantize != "q4_K_M" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if len(req.Files) != 4 || req.Files["config.json"] == "" || req.Files["README.md"] != "" {
+		t.Errorf("Unexpected files: %v", req.Files)
+	}
+	if len(req.Adapters) != 0 {
+		t.Errorf("Expected no adapters, got %v", req.Adapters)
+	}
+	for _, digest := range req.Files {
+		if !blobs[digest] {
+			t.Errorf("Digest %s was not uploaded", digest)
+		}
+	}
+
+	// A second run reuses every blob
+	if _, err := client.CreateFromDirectory(context.Background(), "my-model", dir, nil); err != nil {
+		t.Fatalf("CreateFromDirectory failed: %v", err)
+	}
+	if uploads != 4 {
+		t.Errorf("Expected existing blobs to be skipped, got %d uploads", uploads)
+	}
+}
+
+func TestCreateFromDirectoryAdapter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/api/create":
+			var req CreateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"adapter_model.safetensors": "lora",
+		"adapter_config.json":       "{}",
+	})
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	if _, err := client.CreateFromDirectory(context.Background(), "my-lora", dir, nil); err == nil {
+		t.Fatal("Expected error for adapter without base model")
+	}
+
+	if _, err := client.CreateFromDirectory(context.Background(), "my-lora", dir, &CreateDirectoryOptions{From: "llama3.2"}); err != nil {
+		t.Fatalf("CreateFromDirectory failed: %v", err)
+	}
+
+	req := requests[0]
+	if req.From != "llama3.2" || len(req.Adapters) != 2 || len(req.Files) != 0 {
+		t.Errorf("Unexpected adapter request: %+v", req)
+	}
+}
+
+func TestCreateFromDirectoryGGUF(t *testing.T) {
+	var mu sync.Mutex
+	var requests []CreateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/api/create":
+			var req CreateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			requests = append(requests, req)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"model.Q4_K_M.gguf": "GGUF",
+		"config.json":       "{}",
+	})
+
+	client, _ := NewClient(WithHost(server.URL))
+	if _, err := client.CreateFromDirectory(context.Background(), "gguf-model", dir, nil); err != nil {
+		t.Fatalf("CreateFromDirectory failed: %v", err)
+	}
+
+	req := requests[0]
+	if len(req.Files) != 1 || req.Files["model.Q4_K_M.gguf"] == "" {
+		t.Errorf("Expected only the GGUF file, got %v", req.Files)
+	}
+
+	// Several quantisations side by side are ambiguous
+	writeFiles(t, dir, map[string]string{"model.Q8_0.gguf": "GGUF"})
+	if _, err := client.CreateFromDirectory(context.Background(), "gguf-model", dir, nil); err == nil {
+		t.Error("Expected error for a directory with two GGUF files")
+	}
+
+	nested := t.TempDir()
+	writeFiles(t, nested, map[string]string{"sub/model.gguf": "GGUF"})
+	if _, err := client.CreateFromDirectory(context.Background(), "nested", nested, nil); err == nil {
+		t.Error("Expected error for files outside the top-level directory")
+	}
+
+	if _, err := client.CreateFromDirectory(context.Background(), "empty", t.TempDir(), nil); err == nil {
+		t.Error("Expected error for directory without model files")
+	}
+}
@@ -0,0 +1,74 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// TruncateEmbedding shortens a Matryoshka-style embedding to the given number
+// of dimensions and L2-normalises the result, so cosine and dot-product
+// similarity keep working on the shortened vector.
+// The input slice is not modified. If dimensions is not smaller than the
+// vector length, a normalised copy of the whole vector is returned.
+func TruncateEmbedding(embedding []float64, dimensions int) []float64 {
+	if dimensions <= 0 || dimensions > len(embedding) {
+		dimensions = len(embedding)
+	}
+
+	truncated := make([]float64, dimensions)
+	copy(truncated, embedding[:dimensions])
+
+	var sum float64
+	for _, v := range truncated {
+		sum += v * v
+	}
+	if sum == 0 {
+		return truncated
+	}
+
+	norm := math.Sqrt(sum)
+	for i := range truncated {
+		truncated[i] /= norm
+	}
+
+	return truncated
+}
+
+// truncateEmbeddings applies TruncateEmbedding in place to every vector that
+// is longer than the requested dimensions.
+func truncateEmbeddings(embeddings [][]float64, dimensions int) error {
+	for i, embedding := range embeddings {
+		if len(embedding) < dimensions {
+			return fmt.Errorf("requested %d dimensions but model returned %d", dimensions, len(embedding))
+		}
+		if len(embedding) > dimensions {
+			embeddings[i] = TruncateEmbedding(embedding, dimensions)
+		}
+	}
+	return nil
+}
+
+// ValidateEmbedDimensions checks that the model can produce embeddings with
+// the requested number of dimensions, using the embedding_length reported in
+// the model's metadata.
+func (c *Client) ValidateEmbedDimensions(ctx context.Context, model string, dimensions int) error {
+	if dimensions <= 0 {
+		return fmt.Errorf("invalid embedding dimensions: %d", dimensions)
+	}
+
+	info, err := c.Show(ctx, &ShowRequest{Model: model})
+	if err != nil {
+		return err
+	}
+
+	length := info.EmbeddingLength()
+	if length == 0 {
+		return fmt.Errorf("model %s does not report an embedding length", model)
+	}
+	if dimensions > length {
+		return fmt.Errorf("model %s supports at most %d dimensions, requested %d", model, length, dimensions)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTruncateEmbedding(t *testing.T) {
+	embedding := []float64{3, 4, 12}
+
+	truncated := TruncateEmbedding(embedding, 2)
+	if len(truncated) != 2 {
+		t.Fatalf("Expected 2 dimensions, got %d", len(truncated))
+	}
+	if math.Abs(truncated[0]-0.6) > 1e-9 || math.Abs(truncated[1]-0.8) > 1e-9 {
+		t.Errorf("Expected [0.6 0.8], got %v", truncated)
+	}
+	if embedding[0] != 3 {
+		t.Error("Input embedding should not be modified")
+	}
+
+	zero := TruncateEmbedding([]float64{0, 0, 0}, 2)
+	if len(zero) != 2 || zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("Expected zero vector to stay zero, got %v", zero)
+	}
+}
+
+func TestEmbedDimensions(t *testing.T) {
+	var received EmbedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+
+		// Simulate a server that ignores the dimensions field
+		response := EmbedResponse{
+			Model:      "test-embed-model",
+			Embeddings: [][]float64{{3, 4, 12, 84}, {1, 0, 0, 0}},
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	resp, err := client.Embed(context.Background(), &EmbedRequest{
+		Model:      "test-embed-model",
+		Input:      []string{"a", "b"},
+		Dimensions: 2,
+	})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	if received.Dimensions != 2 {
+		t.Errorf("Expected dimensions 2 to be sent, got %d", received.Dimensions)
+	}
+	for i, embedding := range resp.Embeddings {
+		if len(embedding) != 2 {
+			t.Errorf("Embedding %d: expected 2 dimensions, got %d", i, len(embedding))
+		}
+	}
+	if math.Abs(resp.Embeddings[0][0]-0.6) > 1e-9 {
+		t.Errorf("Expected re-normalised embedding, got %v", resp.Embeddings[0])
+	}
+
+	_, err = client.Embed(context.Background(), &EmbedRequest{Model: "test-embed-model", Input: "a", Dimensions: 8})
+	if err == nil {
+		t.Error("Expected error when model returns fewer dimensions than requested")
+	}
+
+	_, err = client.Embed(context.Background(), &EmbedRequest{Model: "test-embed-model", Input: "a", Dimensions: -1})
+	if err == nil {
+		t.Error("Expected error for negative dimensions")
+	}
+}
+
+func TestValidateEmbedDimensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := ShowResponse{
+			ModelInfo: map[string]interface{}{
+				"general.architecture":        "nomic-bert",
+				"nomic-bert.embedding_length": 768,
+			},
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	ctx := context.Background()
+
+	if err := client.ValidateEmbedDimensions(ctx, "nomic-embed-text", 256); err != nil {
+		t.Errorf("Expected 256 dimensions to be valid, got %v", err)
+	}
+	if err := client.ValidateEmbedDimensions(ctx, "nomic-embed-text", 1024); err == nil {
+		t.Error("Expected error for dimensions above embedding length")
+	}
+	if err := client.ValidateEmbedDimensions(ctx, "nomic-embed-text", 0); err == nil {
+		t.Error("Expected error for zero dimensions")
+	}
+}
@@ -0,0 +1,227 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrDigestMismatch is returned (wrapped) by EnsureModel when the local model
+// does not have the expected digest, even after pulling it.
+var ErrDigestMismatch = errors.New("model digest mismatch")
+
+// EnsureOptions configures EnsureModel.
+type EnsureOptions struct {
+	// Digest, if set, is the manifest digest the local model must have
+	// (with or without the "sha256:" prefix). A model with a different
+	// digest is pulled again.
+	Digest string
+
+	// Insecure allows pulling from registries without TLS.
+	Insecure bool
+
+	// Progress is called for every progress frame while the model is pulled.
+	Progress func(*ProgressResponse)
+
+	// Retry, if set, pulls with PullWithRetry using these options.
+	Retry *RetryOptions
+}
+
+// ensureCall tracks a pull in flight so concurrent EnsureModel calls for the
+// same model share it. The pull runs on its own context, which is cancelled
+// once every caller waiting for it has given up.
+type ensureCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int // guarded by Client.ensureMu
+
+	mu       sync.Mutex
+	progress []func(*ProgressResponse) // nil entries belong to callers that left
+
+	model    *ModelInfo
+	err      error
+	panicked interface{}
+}
+
+// EnsureModel makes sure a model is available locally, pulling it if it is
+// missing (or has the wrong digest) and returns its entry from List.
+//
+// Names are compared after normalisation, so "llama3" matches a local
+// "llama3:latest". Concurrent calls for the same model on one Client share a
+// single pull; callers in other processes are safe as well, since the server
+// serialises pulls of the same blobs.
+//
+// Example:
+//
+//	model, err := client.EnsureModel(ctx, "llama3", &ollama.EnsureOptions{
+//		Progress: func(p *ollama.ProgressResponse) { fmt.Println(p.Status) },
+//	})
+func (c *Client) EnsureModel(ctx context.Context, name string, opts *EnsureOptions) (*ModelInfo, error) {
+	if opts == nil {
+		opts = &EnsureOptions{}
+	}
+
+	ref, err := ParseModelRef(name)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Digest != "" {
+		ref.Digest = normalizeDigest(opts.Digest)
+	}
+
+	if model, err := c.findLocalModel(ctx, ref); err != nil || model != nil {
+		return model, err
+	}
+
+	// Calls that differ only in the digest share one pull; each checks its
+	// own digest afterwards
+	nameRef := ref
+	nameRef.Digest = ""
+	key := nameRef.String()
+	if host := affinityHost(ctx); host != "" {
+		// A Pool ensures the model on one host; don't share across hosts
+		key = host + " " + key
+	}
+
+	c.ensureMu.Lock()
+	if c.ensures == nil {
+		c.ensures = make(map[string]*ensureCall)
+	}
+	call, inFlight := c.ensures[key]
+	if !inFlight {
+		// Detach from the first caller's context: the pull is shared and
+		// must outlive any single caller.
+		pullCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &ensureCall{done: make(chan struct{}), cancel: cancel}
+		c.ensures[key] = call
+		go c.runEnsure(pullCtx, key, nameRef, opts, call)
+	}
+	call.waiters++
+	slot := -1
+	if opts.Progress != nil {
+		call.mu.Lock()
+		slot = len(call.progress)
+		call.progress = append(call.progress, opts.Progress)
+		call.mu.Unlock()
+	}
+	c.ensureMu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.panicked != nil {
+			panic(call.panicked)
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		if ref.Digest != "" && normalizeDigest(call.model.Digest) != ref.Digest {
+			return nil, fmt.Errorf("%w: %s is not %s after pull", ErrDigestMismatch, name, ref.Digest)
+		}
+		return call.model, nil
+	case <-ctx.Done():
+		if slot >= 0 {
+			call.mu.Lock()
+			call.progress[slot] = nil
+			call.mu.Unlock()
+		}
+
+		c.ensureMu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is waiting any more: stop the pull, and let a later
+			// caller start afresh rather than join a cancelled one.
+			call.cancel()
+			if c.ensures[key] == call {
+				delete(c.ensures, key)
+			}
+		}
+		c.ensureMu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// runEnsure performs the shared pull for call and publishes its result.
+func (c *Client) runEnsure(ctx context.Context, key string, ref ModelRef, opts *EnsureOptions, call *ensureCall) {
+	defer func() {
+		// A panicking Progress callback is re-raised in the waiting callers;
+		// either way the call must not stay registered.
+		call.panicked = recover()
+		call.cancel()
+
+		c.ensureMu.Lock()
+		if c.ensures[key] == call {
+			delete(c.ensures, key)
+		}
+		c.ensureMu.Unlock()
+		close(call.done)
+	}()
+
+	call.model, call.err = c.pullModel(ctx, ref, opts, call)
+}
+
+// findLocalModel returns the local model matching ref, or nil if it is not
+// present or its digest differs.
+func (c *Client) findLocalModel(ctx context.Context, ref ModelRef) (*ModelInfo, error) {
+	list, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	model, ok := list.Find(ref.String())
+	if !ok {
+		return nil, nil
+	}
+	return model, nil
+}
+
+// pullModel pulls the model named by ref, which has no digest, and returns
+// its local entry.
+func (c *Client) pullModel(ctx context.Context, ref ModelRef, opts *EnsureOptions, call *ensureCall) (*ModelInfo, error) {
+	name := ref.ShortString()
+	req := &PullRequest{Model: name}
+	if opts.Insecure {
+		req.Insecure = BoolPtr(true)
+	}
+
+	var progressChan <-chan *ProgressResponse
+	var errorChan <-chan error
+	if opts.Retry != nil {
+		progressChan, errorChan = c.PullWithRetry(ctx, req, opts.Retry)
+	} else {
+		progressChan, errorChan = c.PullStream(ctx, req)
+	}
+	var frameErr error
+	for progress := range progressChan {
+		if progress.Error != "" && frameErr == nil {
+			frameErr = &ResponseError{StatusCode: http.StatusOK, Message: progress.Error}
+		}
+		call.mu.Lock()
+		callbacks := make([]func(*ProgressResponse), len(call.progress))
+		copy(callbacks, call.progress)
+		call.mu.Unlock()
+		for _, fn := range callbacks {
+			if fn != nil {
+				fn(progress)
+			}
+		}
+	}
+	err := <-errorChan
+	if err == nil {
+		err = frameErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+
+	model, err := c.findLocalModel(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil {
+		return nil, fmt.Errorf("model %s not found after pull", name)
+	}
+
+	return model, nil
+}
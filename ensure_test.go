@@ -0,0 +1,256 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnsureModelPullsOnce(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	var pulls int32
+	var pulled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			response := ListResponse{Models: []ModelInfo{}}
+			if pulled.Load() {
+				response.Models = append(response.Models, ModelInfo{Model: "llama3:latest", Digest: digest})
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case "/api/pull":
+			atomic.AddInt32(&pulls, 1)
+			time.Sleep(50 * time.Millisecond)
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 10, Completed: 10})
+			pulled.Store(true)
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	var frames int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			model, err := client.EnsureModel(context.Background(), "llama3", &EnsureOptions{
+				Progress: func(*ProgressResponse) { atomic.AddInt32(&frames, 1) },
+			})
+			if err == nil && model.Model != "llama3:latest" {
+				t.Errorf("Unexpected model %q", model.Model)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("EnsureModel failed: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&pulls); n != 1 {
+		t.Errorf("Expected 1 pull, got %d", n)
+	}
+	if atomic.LoadInt32(&frames) == 0 {
+		t.Error("Expected progress callbacks")
+	}
+
+	// Already present: no further pull
+	if _, err := client.EnsureModel(context.Background(), "llama3:latest", &EnsureOptions{Digest: digest}); err != nil {
+		t.Fatalf("EnsureModel failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&pulls); n != 1 {
+		t.Errorf("Expected no additional pull, got %d pulls", n)
+	}
+}
+
+func TestEnsureModelDigestMismatch(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	var pulls int32
+	var pulled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			response := ListResponse{Models: []ModelInfo{}}
+			if pulled.Load() {
+				response.Models = append(response.Models, ModelInfo{Model: "llama3:latest", Digest: digest})
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case "/api/pull":
+			atomic.AddInt32(&pulls, 1)
+			time.Sleep(50 * time.Millisecond)
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 10, Completed: 10})
+			pulled.Store(true)
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	_, err := client.EnsureModel(context.Background(), "llama3", &EnsureOptions{
+		Digest: "sha256:" + strings.Repeat("cd", 32),
+	})
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("Expected ErrDigestMismatch, got %v", err)
+	}
+	if n := atomic.LoadInt32(&pulls); n != 1 {
+		t.Errorf("Expected 1 pull, got %d", n)
+	}
+}
+
+func TestEnsureModelLeaderCancelled(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	var pulls int32
+	var pulled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			response := ListResponse{Models: []ModelInfo{}}
+			if pulled.Load() {
+				response.Models = append(response.Models, ModelInfo{Model: "llama3:latest", Digest: digest})
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case "/api/pull":
+			atomic.AddInt32(&pulls, 1)
+			time.Sleep(50 * time.Millisecond)
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 10, Completed: 10})
+			pulled.Store(true)
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	// The first caller gives up while the pull is running; the second must
+	// still get the model from the same pull
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.EnsureModel(leaderCtx, "llama3", nil)
+		leaderErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.EnsureModel(context.Background(), "llama3", nil)
+		result <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled for the leader, got %v", err)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("EnsureModel failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&pulls); n != 1 {
+		t.Errorf("Expected 1 pull, got %d", n)
+	}
+}
+
+func TestEnsureModelSharesPullAcrossDigests(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	var pulls int32
+	var pulled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			response := ListResponse{Models: []ModelInfo{}}
+			if pulled.Load() {
+				response.Models = append(response.Models, ModelInfo{Model: "llama3:latest", Digest: digest})
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case "/api/pull":
+			atomic.AddInt32(&pulls, 1)
+			time.Sleep(50 * time.Millisecond)
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 10, Completed: 10})
+			pulled.Store(true)
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, want := range []string{"", digest, strings.Repeat("cd", 32)} {
+		wg.Add(1)
+		go func(i int, want string) {
+			defer wg.Done()
+			_, errs[i] = client.EnsureModel(context.Background(), "llama3", &EnsureOptions{Digest: want})
+		}(i, want)
+	}
+	wg.Wait()
+
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("Expected matching calls to succeed, got %v and %v", errs[0], errs[1])
+	}
+	if !errors.Is(errs[2], ErrDigestMismatch) {
+		t.Errorf("Expected ErrDigestMismatch, got %v", errs[2])
+	}
+	if n := atomic.LoadInt32(&pulls); n != 1 {
+		t.Errorf("Expected 1 pull, got %d", n)
+	}
+}
+
+func TestEnsureModelErrorFrame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_ = json.NewEncoder(w).Encode(ListResponse{Models: []ModelInfo{}})
+		case "/api/pull":
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(map[string]string{"error": "pull model manifest: file does not exist"})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	_, err := client.EnsureModel(context.Background(), "missing", nil)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("Expected the server's error, got %v", err)
+	}
+}
+
+func TestEnsureModelInvalidName(t *testing.T) {
+	client, _ := NewClient(WithHost("http://127.0.0.1:0"))
+	if _, err := client.EnsureModel(context.Background(), "bad name", nil); !errors.Is(err, ErrInvalidModelRef) {
+		t.Errorf("Expected ErrInvalidModelRef, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/liliang-cn/ollama-go"
 )
@@ -16,98 +17,32 @@ func main() {
 
 	responseChan, errorChan := ollama.PullStream(ctx, "gemma3")
 
-	var currentDigest string
-	bars := make(map[string]*progressBar)
-
-	for {
-		select {
-		case response, ok := <-responseChan:
-			if !ok {
-				// Channel closed, pull completed
-				fmt.Println("\nPull completed!")
-				// Clean up any remaining progress bars
-				for _, bar := range bars {
-					bar.Finish()
-				}
-				return
-			}
-
-			digest := response.Digest
-
-			// If digest has changed and we have a bar for the previous digest, "close" it
-			if digest != currentDigest && currentDigest != "" {
-				if bar, exists := bars[currentDigest]; exists {
-					bar.Finish()
-				}
-			}
-
-			// If there's no digest, just print the status
-			if digest == "" {
-				fmt.Println(response.Status)
-				continue
-			}
-
-			// Create a new progress bar if we don't have one for this digest and we have a total
-			if _, exists := bars[digest]; !exists && response.Total > 0 {
-				bars[digest] = newProgressBar(response.Total, fmt.Sprintf("pulling %s", digest[7:19]))
-			}
-
-			// Update progress bar if we have one
-			if bar, exists := bars[digest]; exists && response.Completed > 0 {
-				bar.Update(response.Completed)
-			}
-
-			currentDigest = digest
-
-		case err := <-errorChan:
-			if err != nil {
-				log.Fatal(err)
+	// The tracker aggregates per-layer frames so the overall percentage
+	// only moves forward as layers switch
+	tracker := ollama.NewProgressTracker()
+	final := tracker.Track(responseChan, func(ev ollama.ProgressEvent) {
+		if ev.PhaseChanged {
+			if ev.PreviousPhase == ollama.PhaseDownloading {
+				fmt.Println()
 			}
+			fmt.Println(ev.Status)
 		}
-	}
-}
-
-// Simple progress bar implementation
-type progressBar struct {
-	total    int64
-	current  int64
-	desc     string
-	finished bool
-}
-
-func newProgressBar(total int64, desc string) *progressBar {
-	return &progressBar{
-		total: total,
-		desc:  desc,
-	}
-}
-
-func (pb *progressBar) Update(current int64) {
-	if pb.finished {
-		return
-	}
 
-	pb.current = current
-	percent := float64(current) / float64(pb.total) * 100
-
-	// Simple text-based progress indicator
-	fmt.Printf("\r%s: %.1f%% (%s/%s)",
-		pb.desc,
-		percent,
-		formatBytes(current),
-		formatBytes(pb.total))
-}
+		if ev.Phase == ollama.PhaseDownloading && ev.Total > 0 {
+			fmt.Printf("\r%.1f%% (%s/%s) %s/s ETA %s   ",
+				ev.Percent,
+				formatBytes(ev.Completed),
+				formatBytes(ev.Total),
+				formatBytes(int64(ev.Rate)),
+				ev.ETA.Round(time.Second))
+		}
+	})
 
-func (pb *progressBar) Finish() {
-	if pb.finished {
-		return
+	if err := <-errorChan; err != nil {
+		log.Fatal(err)
 	}
 
-	pb.finished = true
-	fmt.Printf("\r%s: 100%% (%s/%s) ✓\n",
-		pb.desc,
-		formatBytes(pb.total),
-		formatBytes(pb.total))
+	fmt.Printf("Pull completed in %s!\n", final.Elapsed.Round(time.Second))
 }
 
 func formatBytes(bytes int64) string {
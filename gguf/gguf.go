@@ -0,0 +1,267 @@
+/*
+Package gguf reads the metadata of local GGUF model files.
+
+Only the header, the key/value metadata and the tensor info table are read;
+tensor data is never loaded, so inspecting a multi-gigabyte file is cheap:
+
+	f, err := gguf.Open("model.Q4_K_M.gguf")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(f.Architecture(), f.ContextLength(), f.FileType())
+
+ModelInfo returns the metadata in the same shape as
+ollama.ShowResponse.ModelInfo, so local files and models on a server can be
+validated by the same code.
+*/
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// magic is "GGUF" read as a little-endian uint32.
+const magic = 0x46554747
+
+// Limits that protect against corrupt or hostile headers.
+const (
+	maxStringLength = 1 << 26
+	maxArrayLength  = 1 << 28
+	maxTensorDims   = 8
+
+	// maxPrealloc bounds how many items are allocated up front from a count
+	// in the header; anything beyond that grows as it is actually read.
+	maxPrealloc = 1024
+)
+
+// ErrInvalidFile is returned (wrapped) when the input is not a valid GGUF file.
+var ErrInvalidFile = errors.New("gguf: invalid file")
+
+// Value types used in the key/value metadata.
+const (
+	typeUint8   uint32 = 0
+	typeInt8    uint32 = 1
+	typeUint16  uint32 = 2
+	typeInt16   uint32 = 3
+	typeUint32  uint32 = 4
+	typeInt32   uint32 = 5
+	typeFloat32 uint32 = 6
+	typeBool    uint32 = 7
+	typeString  uint32 = 8
+	typeArray   uint32 = 9
+	typeUint64  uint32 = 10
+	typeInt64   uint32 = 11
+	typeFloat64 uint32 = 12
+)
+
+// TensorInfo describes a tensor in the file without its data.
+type TensorInfo struct {
+	Name       string
+	Dimensions []uint64
+	Type       TensorType
+	// Offset is relative to the start of the tensor data section.
+	Offset uint64
+}
+
+// Elements returns the number of values in the tensor.
+func (t TensorInfo) Elements() uint64 {
+	n := uint64(1)
+	for _, d := range t.Dimensions {
+		n *= d
+	}
+	return n
+}
+
+// File is the parsed header of a GGUF file.
+type File struct {
+	Version uint32
+	// Metadata holds the key/value pairs with their decoded Go types
+	// (uint32, float32, string, []interface{}, ...).
+	Metadata map[string]interface{}
+	// Keys lists the metadata keys in file order.
+	Keys    []string
+	Tensors []TensorInfo
+}
+
+// Open reads the GGUF header of the file at path.
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("gguf: failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return Decode(file)
+}
+
+// Decode reads a GGUF header from r. Reading stops at the end of the tensor
+// info table.
+func Decode(r io.Reader) (*File, error) {
+	d := &decoder{r: bufio.NewReaderSize(r, 64*1024)}
+
+	if m := d.uint32(); d.err == nil && m != magic {
+		return nil, fmt.Errorf("%w: bad magic %#x", ErrInvalidFile, m)
+	}
+
+	f := &File{Version: d.uint32(), Metadata: make(map[string]interface{})}
+	if d.err == nil && (f.Version < 1 || f.Version > 3) {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFile, f.Version)
+	}
+	d.version = f.Version
+
+	tensorCount := d.count()
+	kvCount := d.count()
+	if d.err != nil {
+		return nil, d.wrap("header")
+	}
+
+	for i := uint64(0); i < kvCount; i++ {
+		key := d.string()
+		value := d.value(d.uint32())
+		if d.err != nil {
+			return nil, d.wrap(fmt.Sprintf("metadata entry %d", i))
+		}
+		if _, ok := f.Metadata[key]; !ok {
+			f.Keys = append(f.Keys, key)
+		}
+		f.Metadata[key] = value
+	}
+
+	if tensorCount > maxArrayLength {
+		return nil, fmt.Errorf("%w: tensor count %d too large", ErrInvalidFile, tensorCount)
+	}
+	f.Tensors = make([]TensorInfo, 0, min(tensorCount, maxPrealloc))
+	for i := uint64(0); i < tensorCount; i++ {
+		t := TensorInfo{Name: d.string()}
+		dims := d.uint32()
+		if d.err == nil && dims > maxTensorDims {
+			return nil, fmt.Errorf("%w: tensor %q has %d dimensions", ErrInvalidFile, t.Name, dims)
+		}
+		for j := uint32(0); j < dims; j++ {
+			t.Dimensions = append(t.Dimensions, d.count())
+		}
+		t.Type = TensorType(d.uint32())
+		t.Offset = d.uint64()
+		if d.err != nil {
+			return nil, d.wrap(fmt.Sprintf("tensor info %d", i))
+		}
+		f.Tensors = append(f.Tensors, t)
+	}
+
+	return f, nil
+}
+
+// decoder reads little-endian GGUF values, remembering the first error.
+type decoder struct {
+	r       io.Reader
+	version uint32
+	buf     [8]byte
+	err     error
+}
+
+func (d *decoder) wrap(what string) error {
+	if errors.Is(d.err, io.EOF) || errors.Is(d.err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated %s", ErrInvalidFile, what)
+	}
+	return fmt.Errorf("gguf: failed to read %s: %w", what, d.err)
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return d.buf[:n]
+	}
+	_, d.err = io.ReadFull(d.r, d.buf[:n])
+	return d.buf[:n]
+}
+
+func (d *decoder) uint8() uint8   { return d.read(1)[0] }
+func (d *decoder) uint16() uint16 { return binary.LittleEndian.Uint16(d.read(2)) }
+func (d *decoder) uint32() uint32 { return binary.LittleEndian.Uint32(d.read(4)) }
+func (d *decoder) uint64() uint64 { return binary.LittleEndian.Uint64(d.read(8)) }
+
+// count reads a length or count, which is 32-bit in version 1 files.
+func (d *decoder) count() uint64 {
+	if d.version == 1 {
+		return uint64(d.uint32())
+	}
+	return d.uint64()
+}
+
+func (d *decoder) string() string {
+	n := d.count()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxStringLength {
+		d.err = fmt.Errorf("%w: string length %d too large", ErrInvalidFile, n)
+		return ""
+	}
+	if n <= maxPrealloc {
+		b := make([]byte, n)
+		_, d.err = io.ReadFull(d.r, b)
+		return string(b)
+	}
+	var b strings.Builder
+	var copied int64
+	copied, d.err = io.CopyN(&b, d.r, int64(n))
+	if d.err == io.EOF && copied < int64(n) {
+		d.err = io.ErrUnexpectedEOF
+	}
+	return b.String()
+}
+
+func (d *decoder) value(t uint32) interface{} {
+	switch t {
+	case typeUint8:
+		return d.uint8()
+	case typeInt8:
+		return int8(d.uint8())
+	case typeUint16:
+		return d.uint16()
+	case typeInt16:
+		return int16(d.uint16())
+	case typeUint32:
+		return d.uint32()
+	case typeInt32:
+		return int32(d.uint32())
+	case typeFloat32:
+		return math.Float32frombits(d.uint32())
+	case typeBool:
+		return d.uint8() != 0
+	case typeString:
+		return d.string()
+	case typeUint64:
+		return d.uint64()
+	case typeInt64:
+		return int64(d.uint64())
+	case typeFloat64:
+		return math.Float64frombits(d.uint64())
+	case typeArray:
+		elemType := d.uint32()
+		n := d.count()
+		if d.err != nil {
+			return nil
+		}
+		if n > maxArrayLength {
+			d.err = fmt.Errorf("%w: array length %d too large", ErrInvalidFile, n)
+			return nil
+		}
+		values := make([]interface{}, 0, min(n, maxPrealloc))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			values = append(values, d.value(elemType))
+		}
+		return values
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: unknown value type %d", ErrInvalidFile, t)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,214 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// encoder writes a minimal GGUF v3 header for tests.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) u32(v uint32) { _ = binary.Write(e, binary.LittleEndian, v) }
+func (e *encoder) u64(v uint64) { _ = binary.Write(e, binary.LittleEndian, v) }
+
+func (e *encoder) str(s string) {
+	e.u64(uint64(len(s)))
+	e.WriteString(s)
+}
+
+func (e *encoder) kv(key string, typ uint32, write func()) {
+	e.str(key)
+	e.u32(typ)
+	write()
+}
+
+func testFile() []byte {
+	e := &encoder{}
+	e.u32(magic)
+	e.u32(3)
+	e.u64(2) // tensors
+	e.u64(7) // metadata entries
+
+	e.kv("general.architecture", typeString, func() { e.str("llama") })
+	e.kv("general.file_type", typeUint32, func() { e.u32(15) })
+	e.kv("llama.context_length", typeUint32, func() { e.u32(8192) })
+	e.kv("llama.embedding_length", typeUint32, func() { e.u32(4096) })
+	e.kv("llama.attention.head_count", typeUint32, func() { e.u32(32) })
+	e.kv("llama.rope.freq_base", typeFloat32, func() { e.u32(math.Float32bits(500000)) })
+	e.kv("tokenizer.ggml.tokens", typeArray, func() {
+		e.u32(typeString)
+		e.u64(6)
+		for _, tok := range []string{"<s>", "</s>", "a", "b", "c", "d"} {
+			e.str(tok)
+		}
+	})
+
+	e.str("token_embd.weight")
+	e.u32(2)
+	e.u64(4096)
+	e.u64(128256)
+	e.u32(12) // Q4_K
+	e.u64(0)
+
+	e.str("output_norm.weight")
+	e.u32(1)
+	e.u64(4096)
+	e.u32(0) // F32
+	e.u64(1 << 20)
+
+	// Tensor data is never read
+	e.Write(make([]byte, 64))
+	return e.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	f, err := Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if f.Version != 3 {
+		t.Errorf("Expected version 3, got %d", f.Version)
+	}
+	if f.Architecture() != "llama" {
+		t.Errorf("Expected llama, got %q", f.Architecture())
+	}
+	if f.ContextLength() != 8192 || f.EmbeddingLength() != 4096 || f.HeadCount() != 32 {
+		t.Errorf("Unexpected lengths: ctx=%d embd=%d heads=%d", f.ContextLength(), f.EmbeddingLength(), f.HeadCount())
+	}
+	if ft, ok := f.FileType(); !ok || ft.String() != "Q4_K_M" {
+		t.Errorf("Expected Q4_K_M, got %v", ft)
+	}
+	if len(f.Keys) != 7 || f.Keys[0] != "general.architecture" {
+		t.Errorf("Unexpected key order: %v", f.Keys)
+	}
+
+	if len(f.Tensors) != 2 {
+		t.Fatalf("Expected 2 tensors, got %d", len(f.Tensors))
+	}
+	if f.Tensors[0].Type.String() != "Q4_K" || f.Tensors[0].Elements() != 4096*128256 {
+		t.Errorf("Unexpected tensor: %+v", f.Tensors[0])
+	}
+	if f.ParameterCount() != 4096*128256+4096 {
+		t.Errorf("Unexpected parameter count %d", f.ParameterCount())
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.gguf")
+	if err := os.WriteFile(path, testFile(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	info := f.ModelInfo(false)
+	if info["llama.context_length"] != float64(8192) {
+		t.Errorf("Expected float64 context length, got %#v", info["llama.context_length"])
+	}
+	if info["llama.rope.freq_base"] != float64(500000) {
+		t.Errorf("Unexpected rope freq base %#v", info["llama.rope.freq_base"])
+	}
+	if tokens := info["tokenizer.ggml.tokens"].([]interface{}); len(tokens) != 0 {
+		t.Errorf("Expected tokens to be omitted, got %d", len(tokens))
+	}
+	if info["general.parameter_count"] != float64(f.ParameterCount()) {
+		t.Errorf("Unexpected parameter count %#v", info["general.parameter_count"])
+	}
+
+	verbose := f.ModelInfo(true)
+	if tokens := verbose["tokenizer.ggml.tokens"].([]interface{}); len(tokens) != 6 || tokens[0] != "<s>" {
+		t.Errorf("Expected all tokens in verbose info, got %v", tokens)
+	}
+
+	details := f.Details()
+	if details.Format != "gguf" || details.Family != "llama" || details.QuantizationLevel != "Q4_K_M" || details.ParameterSize != "525.3M" {
+		t.Errorf("Unexpected details: %+v", details)
+	}
+
+	if f.ShowResponse().ModelInfo["general.architecture"] != "llama" {
+		t.Error("Expected ShowResponse to carry the model info")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := testFile()
+
+	tests := map[string][]byte{
+		"bad magic":   append([]byte("GGML"), valid[4:]...),
+		"truncated":   valid[:40],
+		"empty":       {},
+		"bad version": append(append([]byte{}, valid[:4]...), append([]byte{9, 0, 0, 0}, valid[8:]...)...),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(data))
+			if !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Expected ErrInvalidFile, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.gguf")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// TestDecodeHugeCounts checks that counts in a truncated header are not
+// trusted for allocation.
+func TestDecodeHugeCounts(t *testing.T) {
+	header := func(tensors, kvs uint64) *encoder {
+		e := &encoder{}
+		e.u32(magic)
+		e.u32(3)
+		e.u64(tensors)
+		e.u64(kvs)
+		return e
+	}
+
+	tensors := header(maxArrayLength, 0)
+
+	array := header(0, 1)
+	array.kv("tokenizer.ggml.tokens", typeArray, func() {
+		array.u32(typeUint8)
+		array.u64(maxArrayLength)
+	})
+
+	str := header(0, 1)
+	str.kv("general.name", typeString, func() { str.u64(maxStringLength) })
+
+	tests := map[string][]byte{
+		"tensor count":  tensors.Bytes(),
+		"array length":  array.Bytes(),
+		"string length": str.Bytes(),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Decode(bytes.NewReader(data))
+			runtime.ReadMemStats(&after)
+
+			if !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Expected ErrInvalidFile, got %v", err)
+			}
+			if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+				t.Errorf("Expected a small allocation for a truncated file, got %d bytes", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package gguf
+
+import (
+	"fmt"
+
+	"github.com/liliang-cn/ollama-go"
+)
+
+// Metadata keys used by the accessors.
+const (
+	keyArchitecture   = "general.architecture"
+	keyFileType       = "general.file_type"
+	keyParameterCount = "general.parameter_count"
+	keyTokenizerModel = "tokenizer.ggml.model"
+)
+
+// maxSummaryArrayLength is the longest array kept by ModelInfo when not
+// verbose; longer arrays (token lists, merges) are emptied as the server does.
+const maxSummaryArrayLength = 5
+
+// Architecture returns general.architecture, e.g. "llama" or "qwen2".
+func (f *File) Architecture() string {
+	s, _ := f.Metadata[keyArchitecture].(string)
+	return s
+}
+
+// ContextLength returns <arch>.context_length, or 0 if it is not set.
+func (f *File) ContextLength() int {
+	return f.archInt("context_length")
+}
+
+// EmbeddingLength returns <arch>.embedding_length, or 0 if it is not set.
+func (f *File) EmbeddingLength() int {
+	return f.archInt("embedding_length")
+}
+
+// BlockCount returns <arch>.block_count, or 0 if it is not set.
+func (f *File) BlockCount() int {
+	return f.archInt("block_count")
+}
+
+// HeadCount returns <arch>.attention.head_count, or 0 if it is not set.
+func (f *File) HeadCount() int {
+	return f.archInt("attention.head_count")
+}
+
+// TokenizerModel returns tokenizer.ggml.model, e.g. "gpt2" or "llama".
+func (f *File) TokenizerModel() string {
+	s, _ := f.Metadata[keyTokenizerModel].(string)
+	return s
+}
+
+// FileType returns the quantization type from general.file_type.
+func (f *File) FileType() (FileType, bool) {
+	n, ok := toFloat(f.Metadata[keyFileType])
+	return FileType(n), ok
+}
+
+// ParameterCount returns the total number of tensor elements.
+func (f *File) ParameterCount() uint64 {
+	var n uint64
+	for _, t := range f.Tensors {
+		n += t.Elements()
+	}
+	return n
+}
+
+// ModelInfo returns the metadata in the shape of ollama.ShowResponse.ModelInfo:
+// numbers become float64 and general.parameter_count is filled in. Unless
+// verbose is set, long arrays such as the token list are emptied, matching a
+// non-verbose Show.
+func (f *File) ModelInfo(verbose bool) map[string]interface{} {
+	info := make(map[string]interface{}, len(f.Metadata)+1)
+	for key, value := range f.Metadata {
+		if values, ok := value.([]interface{}); ok && !verbose && len(values) > maxSummaryArrayLength {
+			info[key] = []interface{}{}
+			continue
+		}
+		info[key] = jsonValue(value)
+	}
+	if _, ok := info[keyParameterCount]; !ok {
+		info[keyParameterCount] = float64(f.ParameterCount())
+	}
+	return info
+}
+
+// Details returns the model details the server would report for this file.
+func (f *File) Details() *ollama.ModelDetails {
+	details := &ollama.ModelDetails{
+		Format:        "gguf",
+		Family:        f.Architecture(),
+		ParameterSize: formatParameterCount(f.ParameterCount()),
+	}
+	if details.Family != "" {
+		details.Families = []string{details.Family}
+	}
+	if fileType, ok := f.FileType(); ok {
+		details.QuantizationLevel = fileType.String()
+	}
+	return details
+}
+
+// ShowResponse wraps the file's metadata in a ShowResponse so local files can
+// go through the same validation as models on a server.
+func (f *File) ShowResponse() *ollama.ShowResponse {
+	return &ollama.ShowResponse{
+		Details:   f.Details(),
+		ModelInfo: f.ModelInfo(false),
+	}
+}
+
+func (f *File) archInt(suffix string) int {
+	n, _ := toFloat(f.Metadata[f.Architecture()+"."+suffix])
+	return int(n)
+}
+
+// jsonValue converts a decoded metadata value to the type encoding/json
+// would produce for it.
+func jsonValue(v interface{}) interface{} {
+	if values, ok := v.([]interface{}); ok {
+		out := make([]interface{}, len(values))
+		for i, value := range values {
+			out[i] = jsonValue(value)
+		}
+		return out
+	}
+	if n, ok := toFloat(v); ok {
+		return n
+	}
+	return v
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// formatParameterCount renders a parameter count like the server, e.g. "8.0B".
+func formatParameterCount(n uint64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
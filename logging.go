@@ -0,0 +1,264 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultLogMaxLength = 256
+	redactedImage       = "<image redacted>"
+)
+
+// LogOptions configures request logging.
+type LogOptions struct {
+	// RequestLevel is the level of the line logged when a request is sent
+	// (default Debug).
+	RequestLevel slog.Leveler
+	// ResponseLevel is the level of the line logged when a response has been
+	// read, once per call for streaming responses (default Info).
+	ResponseLevel slog.Leveler
+	// ErrorLevel is the level of the line logged when a request fails
+	// (default Error).
+	ErrorLevel slog.Leveler
+
+	// LogBodies adds the request body and the generated content to the log.
+	// Images are redacted and strings longer than MaxLength are truncated.
+	LogBodies bool
+	// LogHeaders adds the request headers to the log. Authorization, Cookie
+	// and API key headers are masked.
+	LogHeaders bool
+	// MaxLength is the maximum length of logged strings (default 256).
+	MaxLength int
+}
+
+// WithLogger logs every request with default options: the request at Debug,
+// the response or error at Info or Error, without bodies.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return WithLoggerOptions(logger, nil)
+}
+
+// WithLoggerOptions logs every request as configured by opts.
+//
+// Example:
+//
+//	client, err := ollama.NewClient(
+//		ollama.WithLoggerOptions(slog.Default(), &ollama.LogOptions{
+//			LogBodies: true,
+//			MaxLength: 100,
+//		}),
+//	)
+func WithLoggerOptions(logger *slog.Logger, opts *LogOptions) ClientOption {
+	var o LogOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.RequestLevel == nil {
+		o.RequestLevel = slog.LevelDebug
+	}
+	if o.ResponseLevel == nil {
+		o.ResponseLevel = slog.LevelInfo
+	}
+	if o.ErrorLevel == nil {
+		o.ErrorLevel = slog.LevelError
+	}
+	if o.MaxLength <= 0 {
+		o.MaxLength = defaultLogMaxLength
+	}
+
+	return WithMiddleware(loggingMiddleware(logger, o))
+}
+
+func loggingMiddleware(logger *slog.Logger, opts LogOptions) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("endpoint", req.Endpoint),
+			}
+			if model := req.Model(); model != "" {
+				attrs = append(attrs, slog.String("model", model))
+			}
+			if id, ok := RequestIDFromContext(ctx); ok {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			if logger.Enabled(ctx, opts.RequestLevel.Level()) {
+				requestAttrs := attrs
+				if opts.LogHeaders {
+					requestAttrs = append(requestAttrs, slog.Any("headers", maskHeaders(req.Header)))
+				}
+				if opts.LogBodies && req.Body != nil {
+					requestAttrs = append(requestAttrs, slog.Any("body", redactBody(req.Body, opts.MaxLength)))
+				}
+				logger.LogAttrs(ctx, opts.RequestLevel.Level(), "ollama request", requestAttrs...)
+			}
+
+			start := time.Now()
+			resp, err := next(ctx, req)
+			if err != nil {
+				logError(ctx, logger, opts, attrs, err, time.Since(start))
+				return nil, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			ObserveResponse(resp, opts.LogBodies, func(stats ResponseStats) {
+				latency := stats.End.Sub(start)
+				if stats.Err != nil {
+					logError(ctx, logger, opts, attrs, stats.Err, latency)
+					return
+				}
+
+				attrs = append(attrs, slog.Duration("latency", latency))
+				attrs = append(attrs, usageAttrs(start, stats)...)
+				if opts.LogBodies && stats.Content != "" {
+					attrs = append(attrs, slog.String("content", truncateString(stats.Content, opts.MaxLength)))
+				}
+				logger.LogAttrs(ctx, opts.ResponseLevel.Level(), "ollama response", attrs...)
+			})
+			return resp, nil
+		}
+	}
+}
+
+func logError(ctx context.Context, logger *slog.Logger, opts LogOptions, attrs []slog.Attr, err error, latency time.Duration) {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode != http.StatusOK {
+		attrs = append(attrs, slog.Int("status", respErr.StatusCode))
+	}
+	attrs = append(attrs, slog.Duration("latency", latency), slog.String("error", err.Error()))
+	logger.LogAttrs(ctx, opts.ErrorLevel.Level(), "ollama request failed", attrs...)
+}
+
+func usageAttrs(start time.Time, stats ResponseStats) []slog.Attr {
+	var attrs []slog.Attr
+	if stats.Streaming {
+		attrs = append(attrs, slog.Bool("stream", true), slog.Int("frames", stats.Frames))
+		if !stats.FirstToken.IsZero() {
+			attrs = append(attrs, slog.Duration("time_to_first_token", stats.FirstToken.Sub(start)))
+		}
+	}
+
+	usage := stats.Usage
+	if usage.PromptEvalCount > 0 {
+		attrs = append(attrs, slog.Int("prompt_tokens", usage.PromptEvalCount))
+	}
+	if usage.EvalCount > 0 {
+		attrs = append(attrs, slog.Int("eval_tokens", usage.EvalCount))
+	}
+	if rate := usage.TokensPerSecond(); rate > 0 {
+		attrs = append(attrs, slog.Float64("tokens_per_second", rate))
+	}
+	if usage.LoadDuration > 0 {
+		attrs = append(attrs, slog.Duration("load_duration", usage.LoadDuration))
+	}
+	if usage.DoneReason != "" {
+		attrs = append(attrs, slog.String("done_reason", usage.DoneReason))
+	}
+	return attrs
+}
+
+// sensitiveHeaders are masked when headers are logged.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+}
+
+// maskHeaders returns the headers with credentials masked, keeping only the
+// auth scheme, e.g. "Bearer ***".
+func maskHeaders(header http.Header) map[string]string {
+	masked := make(map[string]string, len(header))
+	for key, values := range header {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			if scheme, _, ok := strings.Cut(value, " "); ok {
+				value = scheme + " ***"
+			} else {
+				value = "***"
+			}
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// redactBody returns a loggable copy of a request body: images are replaced
+// by a placeholder and long strings are truncated.
+func redactBody(body interface{}, maxLength int) interface{} {
+	switch b := body.(type) {
+	case *ChatRequest:
+		copied := *b
+		copied.Messages = make([]Message, len(b.Messages))
+		for i, msg := range b.Messages {
+			msg.Images = redactImages(msg.Images)
+			copied.Messages[i] = msg
+		}
+		body = &copied
+	case *GenerateRequest:
+		copied := *b
+		copied.Images = redactImages(b.Images)
+		body = &copied
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Sprintf("<unloggable body: %v>", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return string(data)
+	}
+	return truncateValues(generic, maxLength)
+}
+
+func redactImages(images []Image) []Image {
+	if len(images) == 0 {
+		return images
+	}
+	redacted := make([]Image, len(images))
+	for i := range images {
+		redacted[i] = Image{Data: redactedImage}
+	}
+	return redacted
+}
+
+func truncateValues(v interface{}, maxLength int) interface{} {
+	switch t := v.(type) {
+	case string:
+		if t == redactedImage {
+			return t
+		}
+		return truncateString(t, maxLength)
+	case []interface{}:
+		for i := range t {
+			t[i] = truncateValues(t[i], maxLength)
+		}
+	case map[string]interface{}:
+		for k := range t {
+			t[k] = truncateValues(t[k], maxLength)
+		}
+	}
+	return v
+}
+
+// truncateString shortens s to at most maxLength bytes on a rune boundary,
+// noting how much was cut.
+func truncateString(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d more bytes)", s[:cut], len(s)-cut)
+}
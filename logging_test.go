@@ -0,0 +1,202 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestWithLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/generate":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 0; i < 5; i++ {
+				fmt.Fprintln(w, `{"model":"llama3","response":"word ","done":false}`)
+			}
+			fmt.Fprintln(w, `{"model":"llama3","response":"","done":true,"done_reason":"stop","prompt_eval_count":7,"eval_count":5,"eval_duration":100000000}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, _ := NewClient(WithHost(server.URL), WithLogger(logger))
+
+	responseChan, errorChan := client.GenerateStream(context.Background(), &GenerateRequest{Model: "llama3", Prompt: "hi"})
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if _, err := client.Show(context.Background(), &ShowRequest{Model: "missing"}); err == nil {
+		t.Fatal("Expected Show to fail")
+	}
+
+	lines := decodeLogLines(t, &buf)
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 log lines (one summary per stream), got %d:\n%s", len(lines), buf.String())
+	}
+
+	if lines[0]["msg"] != "ollama request" || lines[0]["level"] != "DEBUG" || lines[0]["endpoint"] != "/api/generate" {
+		t.Errorf("Unexpected request line: %v", lines[0])
+	}
+	if _, ok := lines[0]["body"]; ok {
+		t.Error("Expected no body by default")
+	}
+
+	summary := lines[1]
+	if summary["msg"] != "ollama response" || summary["level"] != "INFO" {
+		t.Errorf("Unexpected response line: %v", summary)
+	}
+	expected := map[string]interface{}{
+		"model":             "llama3",
+		"status":            float64(200),
+		"stream":            true,
+		"frames":            float64(6),
+		"prompt_tokens":     float64(7),
+		"eval_tokens":       float64(5),
+		"tokens_per_second": float64(50),
+		"done_reason":       "stop",
+	}
+	for key, value := range expected {
+		if summary[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, summary[key])
+		}
+	}
+	if _, ok := summary["latency"]; !ok {
+		t.Error("Expected latency")
+	}
+
+	failure := lines[3]
+	if failure["msg"] != "ollama request failed" || failure["level"] != "ERROR" || failure["status"] != float64(404) || failure["error"] == nil {
+		t.Errorf("Unexpected error line: %v", failure)
+	}
+}
+
+func TestWithLoggerWrappedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":"server busy"}`)
+	}))
+	defer server.Close()
+
+	// Middleware below the logger may wrap the server's error
+	wrap := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			if err != nil {
+				return nil, fmt.Errorf("gave up: %w", err)
+			}
+			return resp, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client, _ := NewClient(WithHost(server.URL), WithLogger(logger), WithMiddleware(wrap))
+
+	if _, err := client.List(context.Background()); err == nil {
+		t.Fatal("Expected List to fail")
+	}
+
+	lines := decodeLogLines(t, &buf)
+	if len(lines) != 1 || lines[0]["status"] != float64(503) {
+		t.Errorf("Expected the status of the wrapped error, got %v", lines)
+	}
+}
+
+func TestWithLoggerBodiesAndRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"llava","message":{"role":"assistant","content":"A very long description of the image"},"done":true}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client, _ := NewClient(
+		WithHost(server.URL),
+		WithHeaders(map[string]string{"Authorization": "Bearer secret-token"}),
+		WithLoggerOptions(logger, &LogOptions{
+			RequestLevel: slog.LevelInfo,
+			LogBodies:    true,
+			LogHeaders:   true,
+			MaxLength:    10,
+		}),
+	)
+
+	image := "data:image/png;base64," + strings.Repeat("QUFB", 1000)
+	messages := []Message{
+		{Role: "user", Content: "Describe this picture in detail", Images: []Image{{Data: image}}},
+	}
+	_, err := client.Chat(context.Background(), &ChatRequest{Model: "llava", Messages: messages})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "QUFB") || strings.Contains(out, "secret-token") {
+		t.Fatalf("Expected image and token to be redacted:\n%s", out)
+	}
+
+	lines := decodeLogLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d", len(lines))
+	}
+
+	headers := lines[0]["headers"].(map[string]interface{})
+	if headers["Authorization"] != "Bearer ***" {
+		t.Errorf("Expected masked Authorization, got %v", headers["Authorization"])
+	}
+
+	body := lines[0]["body"].(map[string]interface{})
+	msg := body["messages"].([]interface{})[0].(map[string]interface{})
+	if msg["content"] != "Describe t...(21 more bytes)" {
+		t.Errorf("Expected truncated prompt, got %v", msg["content"])
+	}
+	if images := msg["images"].([]interface{}); len(images) != 1 || images[0] != "<image redacted>" {
+		t.Errorf("Expected redacted image, got %v", images)
+	}
+
+	if lines[1]["content"] != "A very lon...(26 more bytes)" {
+		t.Errorf("Expected truncated content, got %v", lines[1]["content"])
+	}
+
+	if messages[0].Images[0].Data != image {
+		t.Error("Expected the caller's messages to be left untouched")
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	if got := truncateString("short", 10); got != "short" {
+		t.Errorf("Expected unchanged string, got %q", got)
+	}
+	if got := truncateString("héllo", 2); got != "h...(5 more bytes)" {
+		t.Errorf("Expected cut on rune boundary, got %q", got)
+	}
+}
@@ -0,0 +1,369 @@
+/*
+Package metrics collects Prometheus-style metrics from an ollama client.
+
+A Collector is installed as client middleware and serves the metrics in the
+Prometheus text exposition format, without depending on the Prometheus client
+library:
+
+	m := metrics.New(nil)
+	client, err := ollama.NewClient(ollama.WithMiddleware(m.Middleware()))
+	...
+	http.Handle("/metrics", m)
+
+Request counts and latency are recorded for every endpoint. Token counts,
+generation speed, model load time and time to first token are taken from the
+final frame of chat, generate and embed responses. Queue wait times are
+recorded when the collector is given to an ollama.Scheduler:
+
+	scheduler := ollama.NewScheduler(&ollama.SchedulerOptions{OnWait: m.ObserveQueueWait})
+*/
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+// Default histogram buckets.
+var (
+	DefaultLatencyBuckets   = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	DefaultTTFTBuckets      = []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultTokenRateBuckets = []float64{1, 5, 10, 20, 40, 60, 80, 100, 150, 200, 400}
+	DefaultLoadBuckets      = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60}
+	DefaultQueueWaitBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}
+)
+
+// Options configures a Collector. Zero values use the defaults.
+type Options struct {
+	// Namespace prefixes every metric name (default "ollama").
+	Namespace string
+
+	LatencyBuckets   []float64
+	TTFTBuckets      []float64
+	TokenRateBuckets []float64
+	LoadBuckets      []float64
+	QueueWaitBuckets []float64
+}
+
+// Collector records client metrics and serves them over HTTP.
+type Collector struct {
+	mu sync.Mutex
+
+	requests   *vec
+	errors     *vec
+	inFlight   *vec
+	latency    *vec
+	ttft       *vec
+	tokenRate  *vec
+	loadTime   *vec
+	promptToks *vec
+	evalToks   *vec
+	queueWait  *vec
+}
+
+// New creates a Collector.
+func New(opts *Options) *Collector {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Namespace == "" {
+		o.Namespace = "ollama"
+	}
+	buckets := func(b, def []float64) []float64 {
+		if len(b) == 0 {
+			return def
+		}
+		b = append([]float64(nil), b...)
+		sort.Float64s(b)
+		return b
+	}
+	name := func(s string) string { return o.Namespace + "_" + s }
+
+	return &Collector{
+		requests: newVec(name("requests_total"), "Requests by endpoint, model and status code.",
+			kindCounter, nil, "endpoint", "model", "code"),
+		errors: newVec(name("request_errors_total"), "Failed requests by endpoint, model and error type.",
+			kindCounter, nil, "endpoint", "model", "error"),
+		inFlight: newVec(name("requests_in_flight"), "Requests currently in flight, including open streams.",
+			kindGauge, nil, "endpoint"),
+		latency: newVec(name("request_duration_seconds"), "End-to-end request latency, until the response body is read.",
+			kindHistogram, buckets(o.LatencyBuckets, DefaultLatencyBuckets), "endpoint", "model"),
+		ttft: newVec(name("time_to_first_token_seconds"), "Time from sending a streaming request to the first generated token.",
+			kindHistogram, buckets(o.TTFTBuckets, DefaultTTFTBuckets), "endpoint", "model"),
+		tokenRate: newVec(name("eval_tokens_per_second"), "Generation speed computed from eval_count and eval_duration.",
+			kindHistogram, buckets(o.TokenRateBuckets, DefaultTokenRateBuckets), "model"),
+		loadTime: newVec(name("model_load_duration_seconds"), "Model load time reported by the server.",
+			kindHistogram, buckets(o.LoadBuckets, DefaultLoadBuckets), "model"),
+		promptToks: newVec(name("prompt_tokens_total"), "Prompt tokens evaluated.",
+			kindCounter, nil, "model"),
+		evalToks: newVec(name("eval_tokens_total"), "Tokens generated.",
+			kindCounter, nil, "model"),
+		queueWait: newVec(name("queue_wait_seconds"), "Time requests waited in the client-side scheduler queue.",
+			kindHistogram, buckets(o.QueueWaitBuckets, DefaultQueueWaitBuckets), "model", "priority"),
+	}
+}
+
+// Middleware returns the ollama.Middleware that feeds the collector.
+func (c *Collector) Middleware() ollama.Middleware {
+	return func(next ollama.RoundTripFunc) ollama.RoundTripFunc {
+		return func(ctx context.Context, req *ollama.Request) (*http.Response, error) {
+			endpoint := endpointLabel(req.Endpoint)
+			model := req.Model()
+
+			c.add(c.inFlight, 1, endpoint)
+			start := time.Now()
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				c.add(c.inFlight, -1, endpoint)
+				c.recordError(endpoint, model, err)
+				c.observe(c.latency, time.Since(start).Seconds(), endpoint, model)
+				return nil, err
+			}
+
+			ollama.ObserveResponse(resp, false, func(stats ollama.ResponseStats) {
+				c.add(c.inFlight, -1, endpoint)
+				c.record(endpoint, model, resp.StatusCode, start, stats)
+			})
+			return resp, nil
+		}
+	}
+}
+
+func (c *Collector) record(endpoint, model string, status int, start time.Time, stats ollama.ResponseStats) {
+	c.observe(c.latency, stats.End.Sub(start).Seconds(), endpoint, model)
+	if stats.Err != nil {
+		c.recordError(endpoint, model, stats.Err)
+	} else {
+		c.add(c.requests, 1, endpoint, model, strconv.Itoa(status))
+	}
+
+	if stats.Streaming && !stats.FirstToken.IsZero() {
+		c.observe(c.ttft, stats.FirstToken.Sub(start).Seconds(), endpoint, model)
+	}
+
+	usage := stats.Usage
+	if usage.PromptEvalCount > 0 {
+		c.add(c.promptToks, float64(usage.PromptEvalCount), model)
+	}
+	if usage.EvalCount > 0 {
+		c.add(c.evalToks, float64(usage.EvalCount), model)
+	}
+	if rate := usage.TokensPerSecond(); rate > 0 {
+		c.observe(c.tokenRate, rate, model)
+	}
+	if usage.LoadDuration > 0 {
+		c.observe(c.loadTime, usage.LoadDuration.Seconds(), model)
+	}
+}
+
+func (c *Collector) recordError(endpoint, model string, err error) {
+	code := "error"
+	errorType := "transport"
+
+	var respErr *ollama.ResponseError
+	switch {
+	case errors.As(err, &respErr):
+		code = strconv.Itoa(respErr.StatusCode)
+		errorType = "status"
+		if respErr.StatusCode == http.StatusOK {
+			// An error frame in a stream
+			errorType = "stream"
+		}
+	case errors.Is(err, ollama.ErrCircuitOpen):
+		errorType = "circuit_open"
+	case errors.Is(err, context.Canceled):
+		errorType = "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		errorType = "timeout"
+	}
+
+	c.add(c.requests, 1, endpoint, model, code)
+	c.add(c.errors, 1, endpoint, model, errorType)
+}
+
+// ObserveQueueWait records the time a request waited in an ollama.Scheduler
+// queue. Its signature matches ollama.SchedulerOptions.OnWait; the tenant is
+// not used as a label to keep the number of series bounded.
+func (c *Collector) ObserveQueueWait(model string, priority ollama.Priority, tenant string, wait time.Duration) {
+	c.observe(c.queueWait, wait.Seconds(), model, priority.String())
+}
+
+// endpointLabel strips the digest from blob endpoints to keep the label set
+// small.
+func endpointLabel(endpoint string) string {
+	if strings.HasPrefix(endpoint, "/api/blobs/") {
+		return "/api/blobs"
+	}
+	return endpoint
+}
+
+func (c *Collector) add(v *vec, delta float64, labels ...string) {
+	c.mu.Lock()
+	v.series(labels).value += delta
+	c.mu.Unlock()
+}
+
+func (c *Collector) observe(v *vec, value float64, labels ...string) {
+	c.mu.Lock()
+	s := v.series(labels)
+	for i, upper := range v.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+	c.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	c.mu.Lock()
+	for _, v := range []*vec{
+		c.requests, c.errors, c.inFlight, c.latency, c.ttft,
+		c.tokenRate, c.loadTime, c.promptToks, c.evalToks, c.queueWait,
+	} {
+		v.write(&b)
+	}
+	c.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+type kind int
+
+const (
+	kindCounter kind = iota
+	kindGauge
+	kindHistogram
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindGauge:
+		return "gauge"
+	case kindHistogram:
+		return "histogram"
+	}
+	return "counter"
+}
+
+// vec is a metric with one series per label combination.
+type vec struct {
+	name    string
+	help    string
+	kind    kind
+	buckets []float64
+	labels  []string
+	values  map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+
+	// histograms only
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newVec(name, help string, k kind, buckets []float64, labels ...string) *vec {
+	return &vec{name: name, help: help, kind: k, buckets: buckets, labels: labels, values: make(map[string]*series)}
+}
+
+func (v *vec) series(labels []string) *series {
+	key := strings.Join(labels, "\xff")
+	s, ok := v.values[key]
+	if !ok {
+		s = &series{labels: labels}
+		if v.kind == kindHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.values[key] = s
+	}
+	return s
+}
+
+func (v *vec) write(b *strings.Builder) {
+	if len(v.values) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", v.name, v.kind)
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := v.values[key]
+		if v.kind != kindHistogram {
+			fmt.Fprintf(b, "%s%s %s\n", v.name, v.labelString(s.labels, "", 0), formatFloat(s.value))
+			continue
+		}
+
+		for i, upper := range v.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", v.name, v.labelString(s.labels, "le", upper), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", v.name, v.labelString(s.labels, "le", math.Inf(1)), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", v.name, v.labelString(s.labels, "", 0), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", v.name, v.labelString(s.labels, "", 0), s.count)
+	}
+}
+
+// labelString formats the label set, optionally with an extra "le" label.
+func (v *vec) labelString(values []string, extra string, le float64) string {
+	var parts []string
+	for i, name := range v.labels {
+		parts = append(parts, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extra != "" {
+		parts = append(parts, extra+`="`+formatFloat(le)+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value as the text format requires.
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(strings.ToValidUTF8(s, "\uFFFD"))
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
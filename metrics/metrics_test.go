@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+func TestCollector(t *testing.T) {
+	blob := []byte("layer")
+	blobDigest := fmt.Sprintf("%x", sha256.Sum256(blob))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/chat":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"Hi"},"done":false}`)
+			fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":8,"eval_count":20,"eval_duration":500000000,"load_duration":1500000000}`)
+		case "/api/embed":
+			fmt.Fprint(w, `{"model":"nomic","embeddings":[[0.1,0.2]],"prompt_eval_count":3}`)
+		case "/api/blobs/sha256:" + blobDigest:
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model \"x\" not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	m := New(nil)
+	client, _ := ollama.NewClient(ollama.WithHost(server.URL), ollama.WithMiddleware(m.Middleware()))
+	ctx := context.Background()
+
+	responseChan, errorChan := client.ChatStream(ctx, &ollama.ChatRequest{Model: "llama3"})
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if _, err := client.Embed(ctx, &ollama.EmbedRequest{Model: "nomic", Input: "hi"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if _, err := client.CreateBlobFrom(ctx, bytes.NewReader(blob), int64(len(blob)), nil); err != nil {
+		t.Fatalf("CreateBlobFrom failed: %v", err)
+	}
+	if _, err := client.Show(ctx, &ollama.ShowRequest{Model: "x\"y"}); err == nil {
+		t.Fatal("Expected Show to fail")
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	out := rec.Body.String()
+
+	expected := []string{
+		"# TYPE ollama_requests_total counter",
+		`ollama_requests_total{endpoint="/api/chat",model="llama3",code="200"} 1`,
+		`ollama_requests_total{endpoint="/api/embed",model="nomic",code="200"} 1`,
+		`ollama_requests_total{endpoint="/api/blobs",model="",code="201"} 1`,
+		`ollama_requests_total{endpoint="/api/show",model="x\"y",code="404"} 1`,
+		`ollama_request_errors_total{endpoint="/api/show",model="x\"y",error="status"} 1`,
+		`ollama_requests_in_flight{endpoint="/api/chat"} 0`,
+		"# TYPE ollama_request_duration_seconds histogram",
+		`ollama_request_duration_seconds_count{endpoint="/api/embed",model="nomic"} 1`,
+		`ollama_time_to_first_token_seconds_count{endpoint="/api/chat",model="llama3"} 1`,
+		`ollama_eval_tokens_per_second_bucket{model="llama3",le="40"} 1`,
+		`ollama_eval_tokens_per_second_bucket{model="llama3",le="20"} 0`,
+		`ollama_eval_tokens_per_second_sum{model="llama3"} 40`,
+		`ollama_model_load_duration_seconds_bucket{model="llama3",le="+Inf"} 1`,
+		`ollama_model_load_duration_seconds_sum{model="llama3"} 1.5`,
+		`ollama_prompt_tokens_total{model="llama3"} 8`,
+		`ollama_prompt_tokens_total{model="nomic"} 3`,
+		`ollama_eval_tokens_total{model="llama3"} 20`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "ollama_time_to_first_token_seconds_count{endpoint=\"/api/embed\"") {
+		t.Error("Expected no time to first token for non-streaming calls")
+	}
+}
+
+func TestCollectorOptions(t *testing.T) {
+	m := New(&Options{Namespace: "llm", LatencyBuckets: []float64{2, 1}})
+	m.observe(m.latency, 1.5, "/api/tags", "")
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	out := b.String()
+	for _, line := range []string{
+		`llm_request_duration_seconds_bucket{endpoint="/api/tags",model="",le="1"} 0`,
+		`llm_request_duration_seconds_bucket{endpoint="/api/tags",model="",le="2"} 1`,
+		`llm_request_duration_seconds_bucket{endpoint="/api/tags",model="",le="+Inf"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing %q in output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "ollama_") {
+		t.Error("Expected the custom namespace to be used")
+	}
+}
+
+func TestObserveQueueWait(t *testing.T) {
+	m := New(nil)
+	scheduler := ollama.NewScheduler(&ollama.SchedulerOptions{OnWait: m.ObserveQueueWait})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"Hi"},"done":true}`)
+	}))
+	defer server.Close()
+
+	client, _ := ollama.NewClient(ollama.WithHost(server.URL), ollama.WithMiddleware(scheduler.Middleware()))
+	ctx := ollama.ContextWithPriority(context.Background(), ollama.PriorityBatch)
+	if _, err := client.Chat(ctx, &ollama.ChatRequest{Model: "llama3"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	var b strings.Builder
+	_, _ = m.WriteTo(&b)
+	if line := `ollama_queue_wait_seconds_count{model="llama3:latest",priority="batch"} 1`; !strings.Contains(b.String(), line+"\n") {
+		t.Errorf("Missing %q in output:\n%s", line, b.String())
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\\b\"c\nd"); got != `a\\b\"c\nd` {
+		t.Errorf("Unexpected escaping: %s", got)
+	}
+	if endpointLabel("/api/blobs/sha256:abc") != "/api/blobs" {
+		t.Error("Expected blob digests to be stripped")
+	}
+}
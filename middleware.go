@@ -0,0 +1,147 @@
+package ollama
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+// Request is a client request as seen by middleware. Body holds the request
+// struct (e.g. *ChatRequest) and is encoded as JSON after the middleware chain
+// has run, so middleware can inspect or replace it. Blob uploads carry their
+// payload in RawBody instead and leave Body nil.
+type Request struct {
+	Method   string
+	Endpoint string // e.g. "/api/chat"
+	Body     interface{}
+	Header   http.Header
+
+	RawBody       io.Reader
+	ContentLength int64 // length of RawBody, -1 if unknown
+}
+
+// Model returns the model named in the request body, or "" for requests that
+// are not about a single model.
+func (r *Request) Model() string {
+	switch b := r.Body.(type) {
+	case *GenerateRequest:
+		return b.Model
+	case *ChatRequest:
+		return b.Model
+	case *EmbedRequest:
+		return b.Model
+	case *EmbeddingsRequest:
+		return b.Model
+	case *ShowRequest:
+		return b.Model
+	case *PullRequest:
+		return b.Model
+	case *PushRequest:
+		return b.Model
+	case *CreateRequest:
+		return b.Model
+	case *DeleteRequest:
+		return b.Model
+	}
+	return ""
+}
+
+// RoundTripFunc sends a request and returns the server's response. Error
+// statuses are reported as a *ResponseError.
+type RoundTripFunc func(ctx context.Context, req *Request) (*http.Response, error)
+
+// Middleware wraps a RoundTripFunc to observe or change requests and
+// responses. It applies to every request the client makes: JSON calls,
+// streaming calls and blob uploads. For streaming calls the response is
+// returned as soon as the headers arrive and the body is read afterwards.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware adds middleware to the client. The first middleware added is
+// the outermost, i.e. it sees the request first and the response last.
+//
+// Example:
+//
+//	client, err := ollama.NewClient(
+//		ollama.WithMiddleware(
+//			ollama.RequestIDMiddleware(""),
+//			ollama.HeaderMiddleware(map[string]string{"X-Tenant": "acme"}),
+//		),
+//	)
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// DefaultRequestIDHeader is the header used by RequestIDMiddleware when no
+// other header is given.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying a request ID that
+// RequestIDMiddleware sends with every request made with it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestIDMiddleware sends the request ID from the context in the given
+// header (DefaultRequestIDHeader if empty), generating a random ID when the
+// context has none. The ID is added to the context passed down the chain, so
+// later middleware can read it with RequestIDFromContext.
+func RequestIDMiddleware(header string) Middleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			id, ok := RequestIDFromContext(ctx)
+			if !ok {
+				id = newRequestID()
+				ctx = ContextWithRequestID(ctx, id)
+			}
+			req.Header.Set(header, id)
+			return next(ctx, req)
+		}
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// HeaderMiddleware sets fixed headers on every request, replacing any value
+// already set.
+func HeaderMiddleware(headers map[string]string) Middleware {
+	return HeaderFuncMiddleware(func(ctx context.Context) (map[string]string, error) {
+		return headers, nil
+	})
+}
+
+// HeaderFuncMiddleware sets headers computed per request, e.g. a refreshed
+// auth token. If fn fails the request is not sent and the error is returned.
+func HeaderFuncMiddleware(fn func(ctx context.Context) (map[string]string, error)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			headers, err := fn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMiddlewareOrderAndCoverage(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Trace"))
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/api/generate":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprintln(w, `{"response":"hi","done":false}`)
+			fmt.Fprintln(w, `{"response":"","done":true}`)
+		case strings.HasPrefix(r.URL.Path, "/api/blobs/") && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/api/blobs/"):
+			w.WriteHeader(http.StatusCreated)
+		default:
+			_ = json.NewEncoder(w).Encode(ListResponse{})
+		}
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(ctx context.Context, req *Request) (*http.Response, error) {
+				order = append(order, name+">"+req.Endpoint)
+				req.Header.Set("X-Trace", req.Header.Get("X-Trace")+name)
+				resp, err := next(ctx, req)
+				order = append(order, name+"<")
+				return resp, err
+			}
+		}
+	}
+
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(trace("a")), WithMiddleware(trace("b")))
+	ctx := context.Background()
+
+	if _, err := client.List(ctx); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "a>/api/tags,b>/api/tags,b<,a<" {
+		t.Errorf("Unexpected middleware order: %s", got)
+	}
+
+	responseChan, errorChan := client.GenerateStream(ctx, &GenerateRequest{Model: "llama3", Prompt: "hi"})
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	if _, err := client.CreateBlobFrom(ctx, strings.NewReader("blob"), 4, nil); err != nil {
+		t.Fatalf("CreateBlobFrom failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 4 {
+		t.Fatalf("Expected 4 requests, got %v", seen)
+	}
+	for _, s := range seen {
+		if !strings.HasSuffix(s, " ab") {
+			t.Errorf("Request did not pass through middleware: %s", s)
+		}
+	}
+}
+
+func TestMiddlewareRewritesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: req.Model, Done: true})
+	}))
+	defer server.Close()
+
+	rewrite := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			if req.Model() != "alias" {
+				t.Errorf("Expected model alias, got %q", req.Model())
+			}
+			if chat, ok := req.Body.(*ChatRequest); ok {
+				copied := *chat
+				copied.Model = "llama3:8b"
+				req.Body = &copied
+			}
+			return next(ctx, req)
+		}
+	}
+
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(rewrite))
+	resp, err := client.Chat(context.Background(), &ChatRequest{Model: "alias"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Model != "llama3:8b" {
+		t.Errorf("Expected rewritten model, got %q", resp.Model)
+	}
+}
+
+func TestMiddlewareSeesResponseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model not found"}`)
+	}))
+	defer server.Close()
+
+	var status int
+	observe := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			var respErr *ResponseError
+			if errors.As(err, &respErr) {
+				status = respErr.StatusCode
+			}
+			return resp, err
+		}
+	}
+
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(observe))
+	if _, err := client.Show(context.Background(), &ShowRequest{Model: "missing"}); err == nil {
+		t.Fatal("Expected error")
+	}
+	if status != http.StatusNotFound {
+		t.Errorf("Expected middleware to see 404, got %d", status)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(DefaultRequestIDHeader))
+		_ = json.NewEncoder(w).Encode(VersionResponse{})
+	}))
+	defer server.Close()
+
+	var downstream string
+	capture := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			downstream, _ = RequestIDFromContext(ctx)
+			return next(ctx, req)
+		}
+	}
+
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(RequestIDMiddleware(""), capture))
+
+	ctx := ContextWithRequestID(context.Background(), "req-123")
+	if _, err := client.Version(ctx); err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	if _, err := client.Version(context.Background()); err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+
+	if ids[0] != "req-123" {
+		t.Errorf("Expected propagated request ID, got %q", ids[0])
+	}
+	if len(ids[1]) != 32 {
+		t.Errorf("Expected generated request ID, got %q", ids[1])
+	}
+	if downstream != ids[1] {
+		t.Errorf("Expected generated ID %q in context, got %q", ids[1], downstream)
+	}
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	var auth, tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		tenant = r.Header.Get("X-Tenant")
+		_ = json.NewEncoder(w).Encode(VersionResponse{})
+	}))
+	defer server.Close()
+
+	token := 0
+	refresh := HeaderFuncMiddleware(func(ctx context.Context) (map[string]string, error) {
+		token++
+		if token > 1 {
+			return nil, errors.New("refresh failed")
+		}
+		return map[string]string{"Authorization": fmt.Sprintf("Bearer t%d", token)}, nil
+	})
+
+	client, _ := NewClient(
+		WithHost(server.URL),
+		WithHeaders(map[string]string{"Authorization": "Bearer stale"}),
+		WithMiddleware(HeaderMiddleware(map[string]string{"X-Tenant": "acme"}), refresh),
+	)
+
+	if _, err := client.Version(context.Background()); err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	if auth != "Bearer t1" || tenant != "acme" {
+		t.Errorf("Unexpected headers: Authorization=%q X-Tenant=%q", auth, tenant)
+	}
+
+	if _, err := client.Version(context.Background()); err == nil || !strings.Contains(err.Error(), "refresh failed") {
+		t.Errorf("Expected refresh error, got %v", err)
+	}
+}
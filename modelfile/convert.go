@@ -0,0 +1,116 @@
+package modelfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/liliang-cn/ollama-go"
+)
+
+// ToCreateRequest converts the Modelfile into the structured fields of a
+// CreateRequest for the given model name.
+//
+// ADAPTER paths are stored in Adapters keyed by their base file name, which
+// must therefore be unique; local
+// paths must be uploaded with CreateBlob and replaced by their digests before
+// the request is sent.
+func (m *Modelfile) ToCreateRequest(model string) (*ollama.CreateRequest, error) {
+	req := &ollama.CreateRequest{Model: model}
+
+	var licenses []string
+	for _, cmd := range m.Commands {
+		switch cmd.Instruction {
+		case From:
+			req.From = cmd.Value
+		case Template:
+			req.Template = cmd.Value
+		case System:
+			req.System = cmd.Value
+		case License:
+			licenses = append(licenses, cmd.Value)
+		case Adapter:
+			if req.Adapters == nil {
+				req.Adapters = make(map[string]string)
+			}
+			name := filepath.Base(cmd.Value)
+			if _, ok := req.Adapters[name]; ok {
+				return nil, &SyntaxError{Line: cmd.Line, Msg: fmt.Sprintf("duplicate adapter file name %q", name)}
+			}
+			req.Adapters[name] = cmd.Value
+		case Message:
+			req.Messages = append(req.Messages, ollama.Message{Role: cmd.Key, Content: cmd.Value})
+		case Parameter:
+			if req.Parameters == nil {
+				req.Parameters = &ollama.Options{}
+			}
+			if err := req.Parameters.Set(cmd.Key, cmd.Value); err != nil {
+				return nil, &SyntaxError{Line: cmd.Line, Msg: err.Error()}
+			}
+		}
+	}
+
+	switch len(licenses) {
+	case 0:
+	case 1:
+		req.License = licenses[0]
+	default:
+		req.License = licenses
+	}
+
+	return req, nil
+}
+
+// FromCreateRequest builds a Modelfile from the structured fields of a
+// CreateRequest. Commands are emitted in the conventional order: FROM,
+// ADAPTER, TEMPLATE, SYSTEM, PARAMETER, LICENSE and MESSAGE.
+func FromCreateRequest(req *ollama.CreateRequest) (*Modelfile, error) {
+	mf := &Modelfile{}
+	add := func(inst Instruction, key, value string) {
+		mf.Commands = append(mf.Commands, Command{Instruction: inst, Key: key, Value: value})
+	}
+
+	if req.From != "" {
+		add(From, "", req.From)
+	}
+
+	names := make([]string, 0, len(req.Adapters))
+	for name := range req.Adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(Adapter, "", req.Adapters[name])
+	}
+
+	if req.Template != "" {
+		add(Template, "", req.Template)
+	}
+	if req.System != "" {
+		add(System, "", req.System)
+	}
+
+	if req.Parameters != nil {
+		for _, p := range req.Parameters.Parameters() {
+			add(Parameter, p.Name, p.Value)
+		}
+	}
+
+	switch license := req.License.(type) {
+	case nil:
+	case string:
+		add(License, "", license)
+	case []string:
+		for _, l := range license {
+			add(License, "", l)
+		}
+	default:
+		return nil, fmt.Errorf("modelfile: unsupported license type %T", req.License)
+	}
+
+	for _, msg := range req.Messages {
+		add(Message, msg.Role, msg.Content)
+	}
+
+	return mf, nil
+}
@@ -0,0 +1,298 @@
+/*
+Package modelfile parses and renders Ollama Modelfiles.
+
+A Modelfile is parsed into a list of typed commands that can be inspected,
+modified and rendered back to text:
+
+	mf, err := modelfile.ParseString("FROM llama3\nPARAMETER temperature 0.7")
+	if err != nil {
+		log.Fatal(err) // *modelfile.SyntaxError carries the line number
+	}
+	fmt.Print(mf.String())
+
+Use ToCreateRequest and FromCreateRequest to convert between a Modelfile and
+the structured fields of ollama.CreateRequest.
+*/
+package modelfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Instruction identifies the kind of a Modelfile command.
+type Instruction string
+
+// Supported Modelfile instructions.
+const (
+	From      Instruction = "FROM"
+	Parameter Instruction = "PARAMETER"
+	Template  Instruction = "TEMPLATE"
+	System    Instruction = "SYSTEM"
+	Adapter   Instruction = "ADAPTER"
+	License   Instruction = "LICENSE"
+	Message   Instruction = "MESSAGE"
+	Comment   Instruction = "#"
+)
+
+// Command is a single instruction in a Modelfile.
+// Key holds the parameter name for PARAMETER and the role for MESSAGE;
+// Value holds the unquoted argument (or the comment text for comments).
+type Command struct {
+	Instruction Instruction
+	Key         string
+	Value       string
+	Line        int
+}
+
+// Modelfile is the parsed form of a Modelfile.
+type Modelfile struct {
+	Commands []Command
+}
+
+// SyntaxError reports a malformed Modelfile line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("modelfile: line %d: %s", e.Line, e.Msg)
+}
+
+var messageRoles = map[string]bool{
+	"system":    true,
+	"user":      true,
+	"assistant": true,
+}
+
+// Parse reads a Modelfile from r.
+func Parse(r io.Reader) (*Modelfile, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read modelfile: %w", err)
+	}
+
+	p := &parser{lines: lines}
+	return p.parse()
+}
+
+// ParseString parses a Modelfile held in a string, such as
+// ShowResponse.Modelfile or CreateRequest.Modelfile.
+func ParseString(s string) (*Modelfile, error) {
+	return Parse(strings.NewReader(s))
+}
+
+type parser struct {
+	lines []string
+	pos   int
+}
+
+func (p *parser) parse() (*Modelfile, error) {
+	mf := &Modelfile{}
+
+	for ; p.pos < len(p.lines); p.pos++ {
+		lineNo := p.pos + 1
+		line := strings.TrimSpace(p.lines[p.pos])
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			mf.Commands = append(mf.Commands, Command{
+				Instruction: Comment,
+				Value:       strings.TrimSpace(strings.TrimPrefix(line, "#")),
+				Line:        lineNo,
+			})
+			continue
+		}
+
+		name, rest := splitField(line)
+		inst := Instruction(strings.ToUpper(name))
+		cmd := Command{Instruction: inst, Line: lineNo}
+
+		switch inst {
+		case Parameter:
+			key, value := splitField(rest)
+			if key == "" || value == "" {
+				return nil, &SyntaxError{Line: lineNo, Msg: "PARAMETER requires a name and a value"}
+			}
+			v, err := p.value(value)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Key = strings.ToLower(key)
+			cmd.Value = v
+		case Message:
+			role, value := splitField(rest)
+			if role == "" || value == "" {
+				return nil, &SyntaxError{Line: lineNo, Msg: "MESSAGE requires a role and content"}
+			}
+			role = strings.ToLower(role)
+			if !messageRoles[role] {
+				return nil, &SyntaxError{Line: lineNo, Msg: fmt.Sprintf("invalid message role %q", role)}
+			}
+			v, err := p.value(value)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Key = role
+			cmd.Value = v
+		case From, Template, System, Adapter, License:
+			if rest == "" {
+				return nil, &SyntaxError{Line: lineNo, Msg: fmt.Sprintf("%s requires an argument", inst)}
+			}
+			v, err := p.value(rest)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Value = v
+		default:
+			return nil, &SyntaxError{Line: lineNo, Msg: fmt.Sprintf("unknown instruction %q", name)}
+		}
+
+		mf.Commands = append(mf.Commands, cmd)
+	}
+
+	return mf, nil
+}
+
+// value decodes an argument that may be bare, "quoted" or a """triple
+// quoted""" string spanning several lines. It advances p.pos past any extra
+// lines consumed.
+func (p *parser) value(s string) (string, error) {
+	lineNo := p.pos + 1
+
+	if strings.HasPrefix(s, `"""`) {
+		body := strings.TrimPrefix(s, `"""`)
+		if strings.HasSuffix(body, `"""`) {
+			return strings.TrimSuffix(body, `"""`), nil
+		}
+
+		parts := []string{body}
+		for p.pos+1 < len(p.lines) {
+			p.pos++
+			line := strings.TrimRight(p.lines[p.pos], " \t\r")
+			if strings.HasSuffix(line, `"""`) {
+				parts = append(parts, strings.TrimSuffix(line, `"""`))
+				return strings.Join(parts, "\n"), nil
+			}
+			parts = append(parts, p.lines[p.pos])
+		}
+		return "", &SyntaxError{Line: lineNo, Msg: `unterminated """ string`}
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+			return "", &SyntaxError{Line: lineNo, Msg: "unterminated quoted string"}
+		}
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted, nil
+		}
+		return s[1 : len(s)-1], nil
+	}
+
+	return s, nil
+}
+
+// splitField splits s into its first whitespace-delimited field and the
+// trimmed remainder.
+func splitField(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// String renders the Modelfile back to text.
+func (m *Modelfile) String() string {
+	var b strings.Builder
+	for _, cmd := range m.Commands {
+		b.WriteString(cmd.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// String renders a single command as a Modelfile line.
+func (c Command) String() string {
+	switch c.Instruction {
+	case Comment:
+		return "# " + c.Value
+	case Parameter:
+		return fmt.Sprintf("%s %s %s", Parameter, c.Key, quoteParameter(c.Value))
+	case Message:
+		return fmt.Sprintf("%s %s %s", Message, c.Key, quoteText(c.Value))
+	case From, Adapter:
+		return fmt.Sprintf("%s %s", c.Instruction, quoteParameter(c.Value))
+	default:
+		return fmt.Sprintf("%s %s", c.Instruction, quoteText(c.Value))
+	}
+}
+
+// quoteParameter leaves simple tokens such as numbers, model names and paths
+// bare and quotes everything else.
+func quoteParameter(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"\\") || strings.ContainsAny(s, "<>|") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// quoteText renders free-form text. Plain text is "quoted"; text with line
+// breaks, quotes or backslashes is """triple quoted""", which is read back
+// verbatim. Text that triple quotes cannot hold, such as text containing
+// """, is written as a Go-escaped "quoted" string.
+func quoteText(s string) string {
+	if !strings.ContainsAny(s, "\n\"\\\r") {
+		return `"` + s + `"`
+	}
+	if tripleQuotable(s) {
+		return `"""` + s + `"""`
+	}
+	return strconv.Quote(s)
+}
+
+// tripleQuotable reports whether s survives a """triple quoted""" round
+// trip: it must not contain the delimiter or carriage returns, and the first
+// of several lines must not end in whitespace, which the parser trims.
+func tripleQuotable(s string) bool {
+	if strings.Contains(s, `"""`) || strings.Contains(s, "\r") {
+		return false
+	}
+	first, _, multiline := strings.Cut(s, "\n")
+	return !multiline || first == strings.TrimRight(first, " \t")
+}
+
+// Get returns the value of the last command with the given instruction.
+func (m *Modelfile) Get(inst Instruction) (string, bool) {
+	for i := len(m.Commands) - 1; i >= 0; i-- {
+		if m.Commands[i].Instruction == inst {
+			return m.Commands[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Parameters returns the values of every PARAMETER command with the given
+// name, in order. Names are matched case-insensitively.
+func (m *Modelfile) Parameters(name string) []string {
+	var values []string
+	for _, cmd := range m.Commands {
+		if cmd.Instruction == Parameter && strings.EqualFold(cmd.Key, name) {
+			values = append(values, cmd.Value)
+		}
+	}
+	return values
+}
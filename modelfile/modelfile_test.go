@@ -0,0 +1,251 @@
+package modelfile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/liliang-cn/ollama-go"
+)
+
+const sample = `# Mario assistant
+FROM llama3:8b
+PARAMETER temperature 0.7
+PARAMETER stop "<|eot_id|>"
+PARAMETER stop "<|start_header_id|>"
+TEMPLATE """{{ if .System }}<|system|>
+{{ .System }}{{ end }}
+{{ .Prompt }}"""
+SYSTEM You are Mario from Super Mario Bros.
+ADAPTER ./adapters/mario.gguf
+LICENSE "MIT"
+MESSAGE user Who are you?
+MESSAGE assistant "It's-a me, Mario!"
+`
+
+func TestParse(t *testing.T) {
+	mf, err := ParseString(sample)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(mf.Commands) != 11 {
+		t.Fatalf("Expected 11 commands, got %d", len(mf.Commands))
+	}
+
+	if mf.Commands[0].Instruction != Comment || mf.Commands[0].Value != "Mario assistant" {
+		t.Errorf("Unexpected comment: %+v", mf.Commands[0])
+	}
+
+	if from, _ := mf.Get(From); from != "llama3:8b" {
+		t.Errorf("Expected FROM llama3:8b, got %q", from)
+	}
+
+	template, _ := mf.Get(Template)
+	if template != "{{ if .System }}<|system|>\n{{ .System }}{{ end }}\n{{ .Prompt }}" {
+		t.Errorf("Unexpected template: %q", template)
+	}
+
+	if system, _ := mf.Get(System); system != "You are Mario from Super Mario Bros." {
+		t.Errorf("Unexpected system: %q", system)
+	}
+
+	stops := mf.Parameters("stop")
+	if !reflect.DeepEqual(stops, []string{"<|eot_id|>", "<|start_header_id|>"}) {
+		t.Errorf("Unexpected stop parameters: %v", stops)
+	}
+
+	last := mf.Commands[len(mf.Commands)-1]
+	if last.Instruction != Message || last.Key != "assistant" || last.Value != "It's-a me, Mario!" {
+		t.Errorf("Unexpected message: %+v", last)
+	}
+	if last.Line != 13 {
+		t.Errorf("Expected message on line 13, got %d", last.Line)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"unknown instruction", "FROM llama3\nFOO bar", 2},
+		{"missing from argument", "FROM", 1},
+		{"parameter without value", "FROM llama3\nPARAMETER temperature", 2},
+		{"invalid role", "MESSAGE robot hello", 1},
+		{"unterminated triple quote", "FROM llama3\n\nTEMPLATE \"\"\"{{ .Prompt }}\nmore", 3},
+		{"unterminated quote", "SYSTEM \"hello", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseString(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected SyntaxError, got %v", err)
+			}
+			if syntaxErr.Line != tt.line {
+				t.Errorf("Expected error on line %d, got %d (%v)", tt.line, syntaxErr.Line, err)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	mf, err := ParseString(sample)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	rendered := mf.String()
+	again, err := ParseString(rendered)
+	if err != nil {
+		t.Fatalf("Parse of rendered modelfile failed: %v\n%s", err, rendered)
+	}
+
+	if len(again.Commands) != len(mf.Commands) {
+		t.Fatalf("Expected %d commands, got %d", len(mf.Commands), len(again.Commands))
+	}
+	for i := range mf.Commands {
+		a, b := mf.Commands[i], again.Commands[i]
+		if a.Instruction != b.Instruction || a.Key != b.Key || a.Value != b.Value {
+			t.Errorf("Command %d differs after round trip: %+v vs %+v", i, a, b)
+		}
+	}
+
+	if !strings.Contains(rendered, `PARAMETER stop "<|eot_id|>"`) {
+		t.Errorf("Expected quoted stop parameter in:\n%s", rendered)
+	}
+}
+
+func TestRoundTripQuoting(t *testing.T) {
+	values := []string{
+		"plain text",
+		`say "hi"`,
+		`ends with a quote"`,
+		`"`,
+		`literal \n and \t escapes`,
+		`C:\models\llama`,
+		"{{ .System }}\n{{ .Prompt }}",
+		"contains \"\"\" delimiter",
+		"multi\nline with \"\"\"\ndelimiter",
+		"first line   \nsecond line",
+		"windows\r\nline endings",
+		"  padded  ",
+		"",
+	}
+
+	for _, value := range values {
+		for _, inst := range []Instruction{System, Template, License} {
+			mf := &Modelfile{Commands: []Command{{Instruction: inst, Value: value}}}
+			rendered := mf.String()
+
+			again, err := ParseString(rendered)
+			if err != nil {
+				t.Errorf("Parse of %q failed: %v", rendered, err)
+				continue
+			}
+			if got, _ := again.Get(inst); got != value {
+				t.Errorf("%s %q changed after round trip: %q (rendered %q)", inst, value, got, rendered)
+			}
+		}
+
+		mf := &Modelfile{Commands: []Command{{Instruction: Message, Key: "user", Value: value}}}
+		again, err := ParseString(mf.String())
+		if err != nil || again.Commands[0].Value != value {
+			t.Errorf("MESSAGE %q changed after round trip: %+v %v", value, again, err)
+		}
+	}
+}
+
+func TestToCreateRequest(t *testing.T) {
+	mf, err := ParseString(sample)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	req, err := mf.ToCreateRequest("mario")
+	if err != nil {
+		t.Fatalf("ToCreateRequest failed: %v", err)
+	}
+
+	if req.Model != "mario" || req.From != "llama3:8b" {
+		t.Errorf("Unexpected model/from: %q/%q", req.Model, req.From)
+	}
+	if req.Parameters == nil || req.Parameters.Temperature == nil || *req.Parameters.Temperature != 0.7 {
+		t.Errorf("Expected temperature 0.7, got %+v", req.Parameters)
+	}
+	if len(req.Parameters.Stop) != 2 {
+		t.Errorf("Expected 2 stop sequences, got %v", req.Parameters.Stop)
+	}
+	if req.Adapters["mario.gguf"] != "./adapters/mario.gguf" {
+		t.Errorf("Unexpected adapters: %v", req.Adapters)
+	}
+	if req.License != "MIT" {
+		t.Errorf("Unexpected license: %v", req.License)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "user" {
+		t.Errorf("Unexpected messages: %v", req.Messages)
+	}
+
+	bad, err := ParseString("FROM llama3\nPARAMETER temperature hot")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := bad.ToCreateRequest("bad"); err == nil {
+		t.Error("Expected error for invalid parameter value")
+	}
+
+	dup, err := ParseString("FROM llama3\nADAPTER a/adapter.gguf\nADAPTER b/adapter.gguf")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var syntaxErr *SyntaxError
+	if _, err := dup.ToCreateRequest("dup"); !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 {
+		t.Errorf("Expected a SyntaxError on line 3 for a duplicate adapter, got %v", err)
+	}
+}
+
+func TestFromCreateRequest(t *testing.T) {
+	req := &ollama.CreateRequest{
+		Model:  "mario",
+		From:   "llama3",
+		System: "You are Mario.",
+		Parameters: &ollama.Options{
+			NumCtx: ollama.IntPtr(4096),
+			Stop:   []string{"<|eot_id|>"},
+		},
+		License:  []string{"MIT", "Apache-2.0"},
+		Messages: []ollama.Message{{Role: "user", Content: "Hi"}},
+	}
+
+	mf, err := FromCreateRequest(req)
+	if err != nil {
+		t.Fatalf("FromCreateRequest failed: %v", err)
+	}
+
+	expected := `FROM llama3
+SYSTEM "You are Mario."
+PARAMETER num_ctx 4096
+PARAMETER stop "<|eot_id|>"
+LICENSE "MIT"
+LICENSE "Apache-2.0"
+MESSAGE user "Hi"
+`
+	if mf.String() != expected {
+		t.Errorf("Unexpected rendering:\n%s\nwant:\n%s", mf.String(), expected)
+	}
+
+	back, err := mf.ToCreateRequest("mario")
+	if err != nil {
+		t.Fatalf("ToCreateRequest failed: %v", err)
+	}
+	if !reflect.DeepEqual(back.Parameters, req.Parameters) {
+		t.Errorf("Parameters differ after round trip: %+v vs %+v", back.Parameters, req.Parameters)
+	}
+	if !reflect.DeepEqual(back.License, req.License) {
+		t.Errorf("License differs after round trip: %v vs %v", back.License, req.License)
+	}
+}
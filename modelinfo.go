@@ -0,0 +1,83 @@
+package ollama
+
+import "strings"
+
+// Capability is a feature a model supports, as listed in
+// ShowResponse.Capabilities.
+type Capability string
+
+// Capabilities reported by the server.
+const (
+	CapabilityCompletion Capability = "completion"
+	CapabilityTools      Capability = "tools"
+	CapabilityInsert     Capability = "insert"
+	CapabilityVision     Capability = "vision"
+	CapabilityEmbedding  Capability = "embedding"
+	CapabilityThinking   Capability = "thinking"
+)
+
+// HasCapability reports whether the model supports the given capability.
+func (r *ShowResponse) HasCapability(c Capability) bool {
+	for _, capability := range r.Capabilities {
+		if Capability(capability) == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Architecture returns general.architecture from ModelInfo, e.g. "llama".
+func (r *ShowResponse) Architecture() string {
+	s, _ := r.ModelInfo["general.architecture"].(string)
+	return s
+}
+
+// ContextLength returns the model's maximum context length, or 0 if unknown.
+func (r *ShowResponse) ContextLength() int {
+	return r.archInt("context_length")
+}
+
+// EmbeddingLength returns the size of the model's embedding vectors, or 0 if
+// unknown.
+func (r *ShowResponse) EmbeddingLength() int {
+	return r.archInt("embedding_length")
+}
+
+// HeadCount returns the number of attention heads, or 0 if unknown.
+func (r *ShowResponse) HeadCount() int {
+	return r.archInt("attention.head_count")
+}
+
+// BlockCount returns the number of transformer blocks, or 0 if unknown.
+func (r *ShowResponse) BlockCount() int {
+	return r.archInt("block_count")
+}
+
+// ParameterCount returns general.parameter_count, or 0 if unknown.
+func (r *ShowResponse) ParameterCount() int64 {
+	n, _ := r.ModelInfo["general.parameter_count"].(float64)
+	return int64(n)
+}
+
+// ArchValue returns the ModelInfo value for an architecture-prefixed key,
+// e.g. ArchValue("rope.freq_base") reads "llama.rope.freq_base" for a llama
+// model. If the architecture is not reported, any key with the suffix matches.
+func (r *ShowResponse) ArchValue(suffix string) (interface{}, bool) {
+	if arch := r.Architecture(); arch != "" {
+		v, ok := r.ModelInfo[arch+"."+suffix]
+		return v, ok
+	}
+
+	for key, value := range r.ModelInfo {
+		if strings.HasSuffix(key, "."+suffix) && !strings.HasPrefix(key, "general.") {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (r *ShowResponse) archInt(suffix string) int {
+	v, _ := r.ArchValue(suffix)
+	n, _ := v.(float64)
+	return int(n)
+}
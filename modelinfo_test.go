@@ -0,0 +1,74 @@
+package ollama
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const showJSON = `{
+	"capabilities": ["completion", "tools", "thinking"],
+	"model_info": {
+		"general.architecture": "qwen2",
+		"general.parameter_count": 7615616512,
+		"qwen2.attention.head_count": 28,
+		"qwen2.block_count": 28,
+		"qwen2.context_length": 32768,
+		"qwen2.embedding_length": 3584,
+		"qwen2.rope.freq_base": 1000000
+	}
+}`
+
+func TestShowResponseAccessors(t *testing.T) {
+	var show ShowResponse
+	if err := json.Unmarshal([]byte(showJSON), &show); err != nil {
+		t.Fatal(err)
+	}
+
+	if show.Architecture() != "qwen2" {
+		t.Errorf("Expected qwen2, got %q", show.Architecture())
+	}
+	if show.ContextLength() != 32768 {
+		t.Errorf("Expected context length 32768, got %d", show.ContextLength())
+	}
+	if show.EmbeddingLength() != 3584 {
+		t.Errorf("Expected embedding length 3584, got %d", show.EmbeddingLength())
+	}
+	if show.HeadCount() != 28 || show.BlockCount() != 28 {
+		t.Errorf("Expected 28 heads and blocks, got %d and %d", show.HeadCount(), show.BlockCount())
+	}
+	if show.ParameterCount() != 7615616512 {
+		t.Errorf("Unexpected parameter count %d", show.ParameterCount())
+	}
+	if v, ok := show.ArchValue("rope.freq_base"); !ok || v != float64(1000000) {
+		t.Errorf("Unexpected rope.freq_base %v", v)
+	}
+
+	empty := &ShowResponse{}
+	if empty.ContextLength() != 0 || empty.Architecture() != "" || empty.ParameterCount() != 0 {
+		t.Error("Expected zero values for missing model info")
+	}
+
+	// Without general.architecture, any prefixed key matches
+	noArch := &ShowResponse{ModelInfo: map[string]interface{}{"bert.embedding_length": float64(768)}}
+	if noArch.EmbeddingLength() != 768 {
+		t.Errorf("Expected embedding length 768, got %d", noArch.EmbeddingLength())
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	var show ShowResponse
+	if err := json.Unmarshal([]byte(showJSON), &show); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []Capability{CapabilityCompletion, CapabilityTools, CapabilityThinking} {
+		if !show.HasCapability(c) {
+			t.Errorf("Expected capability %q", c)
+		}
+	}
+	for _, c := range []Capability{CapabilityVision, CapabilityEmbedding, CapabilityInsert} {
+		if show.HasCapability(c) {
+			t.Errorf("Did not expect capability %q", c)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Defaults applied to model references that omit a part.
+const (
+	DefaultRegistry  = "registry.ollama.ai"
+	DefaultNamespace = "library"
+	DefaultTag       = "latest"
+)
+
+// ErrInvalidModelRef is returned (wrapped) when a model name cannot be parsed.
+var ErrInvalidModelRef = errors.New("invalid model reference")
+
+// ModelRef is a parsed model name of the form
+//
+//	[host/][namespace/]name[:tag][@digest]
+//
+// e.g. "llama3", "llama3:8b" or "registry.example.com/team/llama3:8b-q4". As
+// with container images, a single leading segment is a host rather than a
+// namespace if it contains "." or ":" or is "localhost".
+type ModelRef struct {
+	Host      string
+	Namespace string
+	Name      string
+	Tag       string
+	Digest    string
+}
+
+// ParseModelRef parses and validates a model name, filling in the default
+// host, namespace and tag for parts that are omitted.
+func ParseModelRef(s string) (ModelRef, error) {
+	invalid := func(reason string) (ModelRef, error) {
+		return ModelRef{}, fmt.Errorf("%w %q: %s", ErrInvalidModelRef, s, reason)
+	}
+
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return invalid("empty name")
+	}
+
+	var ref ModelRef
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		ref.Digest = strings.ToLower(strings.Replace(rest[i+1:], "-", ":", 1))
+		rest = rest[:i]
+		if !validDigest(ref.Digest) {
+			return invalid("digest must be sha256:<64 hex characters>")
+		}
+	}
+
+	parts := strings.Split(rest, "/")
+	switch len(parts) {
+	case 1:
+		ref.Name = parts[0]
+	case 2:
+		if looksLikeHost(parts[0]) {
+			ref.Host, ref.Name = parts[0], parts[1]
+		} else {
+			ref.Namespace, ref.Name = parts[0], parts[1]
+		}
+	case 3:
+		ref.Host, ref.Namespace, ref.Name = parts[0], parts[1], parts[2]
+	default:
+		return invalid("too many path segments")
+	}
+
+	if i := strings.LastIndex(ref.Name, ":"); i >= 0 {
+		ref.Name, ref.Tag = ref.Name[:i], ref.Name[i+1:]
+		if ref.Tag == "" {
+			return invalid("empty tag")
+		}
+	}
+
+	if ref.Name == "" {
+		if ref.Digest != "" && len(parts) == 1 {
+			// A bare "@sha256:..." reference selects a model by digest only
+			return ref, nil
+		}
+		return invalid("empty model name")
+	}
+
+	if ref.Host == "" {
+		ref.Host = DefaultRegistry
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = DefaultNamespace
+	}
+	if ref.Tag == "" {
+		ref.Tag = DefaultTag
+	}
+
+	if !validHost(ref.Host) {
+		return invalid(fmt.Sprintf("invalid host %q", ref.Host))
+	}
+	if !validPart(ref.Namespace, 80) {
+		return invalid(fmt.Sprintf("invalid namespace %q", ref.Namespace))
+	}
+	if !validPart(ref.Name, 80) {
+		return invalid(fmt.Sprintf("invalid name %q", ref.Name))
+	}
+	if !validPart(ref.Tag, 80) {
+		return invalid(fmt.Sprintf("invalid tag %q", ref.Tag))
+	}
+
+	return ref, nil
+}
+
+// looksLikeHost reports whether the first of two path segments is a registry
+// host rather than a namespace, as in "localhost:11434/llama3" or
+// "registry.example.com/llama3".
+func looksLikeHost(segment string) bool {
+	return strings.ContainsAny(segment, ".:") || segment == "localhost"
+}
+
+// MustParseModelRef is like ParseModelRef but panics on error.
+func MustParseModelRef(s string) ModelRef {
+	ref, err := ParseModelRef(s)
+	if err != nil {
+		panic(err)
+	}
+	return ref
+}
+
+// String returns the fully qualified, lower-cased form of the reference,
+// e.g. "registry.ollama.ai/library/llama3:latest".
+func (r ModelRef) String() string {
+	var b strings.Builder
+	if r.Name != "" {
+		b.WriteString(strings.ToLower(r.Host + "/" + r.Namespace + "/" + r.Name + ":" + r.Tag))
+	}
+	if r.Digest != "" {
+		b.WriteString("@" + r.Digest)
+	}
+	return b.String()
+}
+
+// ShortString returns the reference without the default host and namespace,
+// matching how the server lists models, e.g. "llama3:latest" or
+// "team/llama3:8b".
+func (r ModelRef) ShortString() string {
+	if r.Name == "" {
+		return r.String()
+	}
+
+	var b strings.Builder
+	if !strings.EqualFold(r.Host, DefaultRegistry) {
+		b.WriteString(r.Host + "/" + r.Namespace + "/")
+	} else if !strings.EqualFold(r.Namespace, DefaultNamespace) {
+		b.WriteString(r.Namespace + "/")
+	}
+	b.WriteString(r.Name + ":" + r.Tag)
+	if r.Digest != "" {
+		b.WriteString("@" + r.Digest)
+	}
+	return strings.ToLower(b.String())
+}
+
+// Equal reports whether two references name the same model. Names are
+// compared case-insensitively; digests are only compared when both sides
+// have one.
+func (r ModelRef) Equal(other ModelRef) bool {
+	if r.Digest != "" && other.Digest != "" && r.Digest != other.Digest {
+		return false
+	}
+	if r.Name == "" || other.Name == "" {
+		return r.Digest != "" && r.Digest == other.Digest
+	}
+	return strings.EqualFold(r.Host, other.Host) &&
+		strings.EqualFold(r.Namespace, other.Namespace) &&
+		strings.EqualFold(r.Name, other.Name) &&
+		strings.EqualFold(r.Tag, other.Tag)
+}
+
+// SameModel reports whether two model names refer to the same model after
+// normalisation. Unparseable names never match.
+func SameModel(a, b string) bool {
+	refA, err := ParseModelRef(a)
+	if err != nil {
+		return false
+	}
+	refB, err := ParseModelRef(b)
+	if err != nil {
+		return false
+	}
+	return refA.Equal(refB)
+}
+
+// Ref parses the model's name, attaching its digest.
+func (m ModelInfo) Ref() (ModelRef, error) {
+	ref, err := ParseModelRef(m.Model)
+	if err != nil {
+		return ModelRef{}, err
+	}
+	if ref.Digest == "" && m.Digest != "" {
+		ref.Digest = normalizeDigest(m.Digest)
+	}
+	return ref, nil
+}
+
+// Find returns the listed model matching name after normalisation, so
+// "llama3", "llama3:latest" and "registry.ollama.ai/library/llama3:latest"
+// all find the same entry.
+func (r *ListResponse) Find(name string) (*ModelInfo, bool) {
+	want, err := ParseModelRef(name)
+	if err != nil {
+		return nil, false
+	}
+
+	for i := range r.Models {
+		ref, err := r.Models[i].Ref()
+		if err == nil && want.Equal(ref) {
+			return &r.Models[i], true
+		}
+	}
+	return nil, false
+}
+
+// Find returns the running model matching name after normalisation.
+func (r *ProcessResponse) Find(name string) (*ProcessModel, bool) {
+	want, err := ParseModelRef(name)
+	if err != nil {
+		return nil, false
+	}
+
+	for i := range r.Models {
+		model := r.Models[i].Model
+		if model == "" {
+			model = r.Models[i].Name
+		}
+		ref, err := ParseModelRef(model)
+		if err != nil {
+			continue
+		}
+		if r.Models[i].Digest != "" {
+			ref.Digest = normalizeDigest(r.Models[i].Digest)
+		}
+		if want.Equal(ref) {
+			return &r.Models[i], true
+		}
+	}
+	return nil, false
+}
+
+// normalizeDigest returns a digest in "sha256:<hex>" form, accepting bare
+// hex as returned by /api/tags and the "sha256-<hex>" blob file form.
+func normalizeDigest(digest string) string {
+	digest = strings.ToLower(strings.Replace(digest, "sha256-", "sha256:", 1))
+	if !strings.HasPrefix(digest, "sha256:") {
+		digest = "sha256:" + digest
+	}
+	return digest
+}
+
+func validDigest(digest string) bool {
+	hex := strings.TrimPrefix(digest, "sha256:")
+	if hex == digest || len(hex) != 64 {
+		return false
+	}
+	for _, c := range hex {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func validHost(host string) bool {
+	if host == "" || len(host) > 350 {
+		return false
+	}
+	for _, c := range host {
+		if !(isAlphanumeric(c) || c == '.' || c == '-' || c == '_' || c == ':') {
+			return false
+		}
+	}
+	return isAlphanumeric(rune(host[0]))
+}
+
+func validPart(part string, maxLen int) bool {
+	if part == "" || len(part) > maxLen || !isAlphanumeric(rune(part[0])) {
+		return false
+	}
+	for _, c := range part {
+		if !(isAlphanumeric(c) || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
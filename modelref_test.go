@@ -0,0 +1,151 @@
+package ollama
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseModelRef(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		input    string
+		expected ModelRef
+		short    string
+	}{
+		{
+			input:    "llama3",
+			expected: ModelRef{Host: DefaultRegistry, Namespace: DefaultNamespace, Name: "llama3", Tag: DefaultTag},
+			short:    "llama3:latest",
+		},
+		{
+			input:    "llama3:8b",
+			expected: ModelRef{Host: DefaultRegistry, Namespace: DefaultNamespace, Name: "llama3", Tag: "8b"},
+			short:    "llama3:8b",
+		},
+		{
+			input:    "team/llama3",
+			expected: ModelRef{Host: DefaultRegistry, Namespace: "team", Name: "llama3", Tag: DefaultTag},
+			short:    "team/llama3:latest",
+		},
+		{
+			input:    "registry.example.com:5000/team/llama3:8b-q4",
+			expected: ModelRef{Host: "registry.example.com:5000", Namespace: "team", Name: "llama3", Tag: "8b-q4"},
+			short:    "registry.example.com:5000/team/llama3:8b-q4",
+		},
+		{
+			input:    "localhost:11434/llama3",
+			expected: ModelRef{Host: "localhost:11434", Namespace: DefaultNamespace, Name: "llama3", Tag: DefaultTag},
+			short:    "localhost:11434/library/llama3:latest",
+		},
+		{
+			input:    "registry.example.com/llama3:8b",
+			expected: ModelRef{Host: "registry.example.com", Namespace: DefaultNamespace, Name: "llama3", Tag: "8b"},
+			short:    "registry.example.com/library/llama3:8b",
+		},
+		{
+			input:    "localhost/llama3",
+			expected: ModelRef{Host: "localhost", Namespace: DefaultNamespace, Name: "llama3", Tag: DefaultTag},
+			short:    "localhost/library/llama3:latest",
+		},
+		{
+			input:    "llama3@" + digest,
+			expected: ModelRef{Host: DefaultRegistry, Namespace: DefaultNamespace, Name: "llama3", Tag: DefaultTag, Digest: digest},
+			short:    "llama3:latest@" + digest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ref, err := ParseModelRef(tt.input)
+			if err != nil {
+				t.Fatalf("ParseModelRef failed: %v", err)
+			}
+			if ref != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, ref)
+			}
+			if ref.ShortString() != tt.short {
+				t.Errorf("Expected short form %q, got %q", tt.short, ref.ShortString())
+			}
+		})
+	}
+
+	ref := MustParseModelRef("Llama3")
+	if ref.String() != "registry.ollama.ai/library/llama3:latest" {
+		t.Errorf("Unexpected canonical form: %s", ref.String())
+	}
+}
+
+func TestParseModelRefErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"llama3:",
+		"a/b/c/d",
+		"-llama3",
+		"llama 3",
+		"llama3@sha256:1234",
+		"team/",
+	}
+
+	for _, input := range tests {
+		_, err := ParseModelRef(input)
+		if !errors.Is(err, ErrInvalidModelRef) {
+			t.Errorf("Expected ErrInvalidModelRef for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestModelRefEqual(t *testing.T) {
+	same := [][2]string{
+		{"llama3", "llama3:latest"},
+		{"llama3", "registry.ollama.ai/library/llama3:latest"},
+		{"LLaMA3:8B", "llama3:8b"},
+	}
+	for _, pair := range same {
+		if !SameModel(pair[0], pair[1]) {
+			t.Errorf("Expected %q and %q to match", pair[0], pair[1])
+		}
+	}
+
+	different := [][2]string{
+		{"llama3", "llama3:8b"},
+		{"llama3", "team/llama3"},
+		{"llama3", "invalid name"},
+	}
+	for _, pair := range different {
+		if SameModel(pair[0], pair[1]) {
+			t.Errorf("Expected %q and %q not to match", pair[0], pair[1])
+		}
+	}
+}
+
+func TestListResponseFind(t *testing.T) {
+	list := &ListResponse{
+		Models: []ModelInfo{
+			{Model: "llama3:latest", Digest: strings.Repeat("ab", 32)},
+			{Model: "registry.example.com/team/llama3:8b-q4"},
+		},
+	}
+
+	if m, ok := list.Find("llama3"); !ok || m.Model != "llama3:latest" {
+		t.Errorf("Expected to find llama3:latest, got %v", m)
+	}
+	if m, ok := list.Find("registry.example.com/team/llama3:8b-q4"); !ok || m != &list.Models[1] {
+		t.Errorf("Expected to find the registry model, got %v", m)
+	}
+	if _, ok := list.Find("llama3:8b"); ok {
+		t.Error("Did not expect to find llama3:8b")
+	}
+	if _, ok := list.Find("llama3@sha256:" + strings.Repeat("cd", 32)); ok {
+		t.Error("Did not expect a match with a different digest")
+	}
+	if _, ok := list.Find("llama3@sha256:" + strings.Repeat("ab", 32)); !ok {
+		t.Error("Expected a match with the same digest")
+	}
+
+	ps := &ProcessResponse{Models: []ProcessModel{{Name: "llama3:latest", Model: "llama3:latest"}}}
+	if _, ok := ps.Find("llama3"); !ok {
+		t.Error("Expected to find running llama3")
+	}
+}
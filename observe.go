@@ -0,0 +1,212 @@
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage holds the timings and token counts reported in the final frame of a
+// generate, chat or embed response.
+type Usage struct {
+	Model              string
+	Done               bool
+	DoneReason         string
+	TotalDuration      time.Duration
+	LoadDuration       time.Duration
+	PromptEvalCount    int
+	PromptEvalDuration time.Duration
+	EvalCount          int
+	EvalDuration       time.Duration
+}
+
+// TokensPerSecond returns the generation speed, or 0 if it is not known.
+func (u Usage) TokensPerSecond() float64 {
+	if u.EvalCount == 0 || u.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(u.EvalCount) / u.EvalDuration.Seconds()
+}
+
+// PromptTokensPerSecond returns the prompt processing speed, or 0 if it is
+// not known.
+func (u Usage) PromptTokensPerSecond() float64 {
+	if u.PromptEvalCount == 0 || u.PromptEvalDuration <= 0 {
+		return 0
+	}
+	return float64(u.PromptEvalCount) / u.PromptEvalDuration.Seconds()
+}
+
+// ResponseStats describes a response body after it has been read and closed.
+type ResponseStats struct {
+	// Streaming is true for NDJSON responses.
+	Streaming bool
+	// FirstByte is when the first body byte was read, FirstToken when the
+	// first frame with generated content (or thinking) was read. Both are
+	// zero if that never happened.
+	FirstByte  time.Time
+	FirstToken time.Time
+	End        time.Time
+	Bytes      int64
+	Frames     int
+	// Usage is taken from the last frame that carried timings or counts.
+	Usage Usage
+	// Content is the generated text, collected only when requested.
+	Content string
+	// Err is the error returned while reading the body, or an error frame
+	// sent by the server. io.EOF is not reported.
+	Err error
+}
+
+// ObserveResponse wraps resp.Body so that fn is called once with the
+// response's stats when the body has been read to the end or closed. It is
+// meant for middleware that needs token counts or time-to-first-token of
+// both JSON and streaming responses. If collectContent is set, the generated
+// text is gathered into ResponseStats.Content.
+func ObserveResponse(resp *http.Response, collectContent bool, fn func(ResponseStats)) {
+	resp.Body = &observedBody{
+		body:           resp.Body,
+		collectContent: collectContent,
+		fn:             fn,
+		stats: ResponseStats{
+			Streaming: strings.Contains(resp.Header.Get("Content-Type"), "ndjson"),
+		},
+	}
+}
+
+// observedFrame is the subset of a response frame the observer reads.
+type observedFrame struct {
+	Model              string `json:"model"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason"`
+	TotalDuration      int64  `json:"total_duration"`
+	LoadDuration       int64  `json:"load_duration"`
+	PromptEvalCount    int    `json:"prompt_eval_count"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration"`
+	EvalCount          int    `json:"eval_count"`
+	EvalDuration       int64  `json:"eval_duration"`
+	Response           string `json:"response"`
+	Thinking           string `json:"thinking"`
+	Message            struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Error string `json:"error"`
+}
+
+type observedBody struct {
+	body           io.ReadCloser
+	collectContent bool
+	fn             func(ResponseStats)
+
+	mu      sync.Mutex
+	buf     []byte
+	content strings.Builder
+	stats   ResponseStats
+	once    sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+
+	b.mu.Lock()
+	if n > 0 {
+		if b.stats.FirstByte.IsZero() {
+			b.stats.FirstByte = time.Now()
+		}
+		b.stats.Bytes += int64(n)
+		b.buf = append(b.buf, p[:n]...)
+		b.scanLines()
+	}
+	if err != nil && err != io.EOF && b.stats.Err == nil {
+		b.stats.Err = err
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.body.Close()
+	b.finish()
+	return err
+}
+
+// scanLines processes every complete line in the buffer. Must hold b.mu.
+func (b *observedBody) scanLines() {
+	for {
+		i := bytes.IndexByte(b.buf, '\n')
+		if i < 0 {
+			return
+		}
+		b.frame(b.buf[:i])
+		b.buf = b.buf[i+1:]
+	}
+}
+
+// frame records a single JSON frame. Must hold b.mu.
+func (b *observedBody) frame(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var f observedFrame
+	if json.Unmarshal(line, &f) != nil {
+		return
+	}
+	b.stats.Frames++
+
+	if f.Error != "" && b.stats.Err == nil {
+		b.stats.Err = &ResponseError{StatusCode: http.StatusOK, Message: f.Error}
+	}
+
+	content := f.Response + f.Message.Content
+	if b.stats.FirstToken.IsZero() && (content != "" || f.Thinking != "" || f.Message.Thinking != "") {
+		b.stats.FirstToken = time.Now()
+	}
+	if b.collectContent {
+		b.content.WriteString(content)
+	}
+
+	if f.Model != "" {
+		b.stats.Usage.Model = f.Model
+	}
+	if f.Done || f.TotalDuration > 0 || f.PromptEvalCount > 0 || f.EvalCount > 0 {
+		model := b.stats.Usage.Model
+		b.stats.Usage = Usage{
+			Model:              model,
+			Done:               f.Done,
+			DoneReason:         f.DoneReason,
+			TotalDuration:      time.Duration(f.TotalDuration),
+			LoadDuration:       time.Duration(f.LoadDuration),
+			PromptEvalCount:    f.PromptEvalCount,
+			PromptEvalDuration: time.Duration(f.PromptEvalDuration),
+			EvalCount:          f.EvalCount,
+			EvalDuration:       time.Duration(f.EvalDuration),
+		}
+	}
+}
+
+// finish reports the stats once, treating any unterminated data as the last
+// frame.
+func (b *observedBody) finish() {
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.frame(b.buf)
+		b.buf = nil
+		b.stats.End = time.Now()
+		b.stats.Content = b.content.String()
+		stats := b.stats
+		b.mu.Unlock()
+
+		b.fn(stats)
+	})
+}
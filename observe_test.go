@@ -0,0 +1,127 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func observingClient(t *testing.T, handler http.HandlerFunc, collect bool, stats chan<- ResponseStats) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	observe := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			resp, err := next(ctx, req)
+			if err == nil {
+				ObserveResponse(resp, collect, func(s ResponseStats) { stats <- s })
+			}
+			return resp, err
+		}
+	}
+
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(observe))
+	return client
+}
+
+func TestObserveResponseJSON(t *testing.T) {
+	stats := make(chan ResponseStats, 1)
+	client := observingClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"model":"llama3","response":"Hi there","done":true,"done_reason":"stop","prompt_eval_count":4,"prompt_eval_duration":200000000,"eval_count":10,"eval_duration":500000000,"load_duration":1000000}`)
+	}, true, stats)
+
+	if _, err := client.Generate(context.Background(), &GenerateRequest{Model: "llama3", Prompt: "Hi"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	s := <-stats
+	if s.Streaming {
+		t.Error("Expected a non-streaming response")
+	}
+	if s.Frames != 1 || s.Content != "Hi there" {
+		t.Errorf("Unexpected frames %d or content %q", s.Frames, s.Content)
+	}
+	u := s.Usage
+	if u.Model != "llama3" || !u.Done || u.DoneReason != "stop" || u.PromptEvalCount != 4 || u.EvalCount != 10 {
+		t.Errorf("Unexpected usage: %+v", u)
+	}
+	if u.LoadDuration != time.Millisecond {
+		t.Errorf("Expected load duration 1ms, got %v", u.LoadDuration)
+	}
+	if u.TokensPerSecond() != 20 || u.PromptTokensPerSecond() != 20 {
+		t.Errorf("Unexpected rates: %v %v", u.TokensPerSecond(), u.PromptTokensPerSecond())
+	}
+	if s.FirstByte.IsZero() || s.End.Before(s.FirstByte) {
+		t.Errorf("Unexpected timestamps: %v %v", s.FirstByte, s.End)
+	}
+}
+
+func TestObserveResponseStream(t *testing.T) {
+	stats := make(chan ResponseStats, 1)
+	client := observingClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":false}`)
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"A"},"done":false}`)
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"eval_count":1}`)
+	}, false, stats)
+
+	responseChan, errorChan := client.ChatStream(context.Background(), &ChatRequest{Model: "llama3"})
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	s := <-stats
+	if !s.Streaming || s.Frames != 3 {
+		t.Errorf("Expected 3 streamed frames, got streaming=%v frames=%d", s.Streaming, s.Frames)
+	}
+	if s.Content != "" {
+		t.Errorf("Expected no content when not collecting, got %q", s.Content)
+	}
+	if s.FirstToken.Sub(s.FirstByte) < 10*time.Millisecond {
+		t.Errorf("Expected first token after the empty frame, got %v", s.FirstToken.Sub(s.FirstByte))
+	}
+	if !s.Usage.Done || s.Usage.EvalCount != 1 {
+		t.Errorf("Unexpected usage: %+v", s.Usage)
+	}
+}
+
+func TestObserveResponseErrorFrame(t *testing.T) {
+	stats := make(chan ResponseStats, 1)
+	resp := &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/x-ndjson"}},
+		Body:   nopCloser{strings.NewReader("{\"status\":\"pulling\"}\n{\"error\":\"disk full\"}\n")},
+	}
+	ObserveResponse(resp, false, func(s ResponseStats) { stats <- s })
+
+	buf := make([]byte, 4)
+	for {
+		if _, err := resp.Body.Read(buf); err != nil {
+			break
+		}
+	}
+	resp.Body.Close()
+
+	s := <-stats
+	if s.Err == nil || !strings.Contains(s.Err.Error(), "disk full") {
+		t.Errorf("Expected error frame to be reported, got %v", s.Err)
+	}
+	select {
+	case <-stats:
+		t.Error("Expected stats to be reported once")
+	default:
+	}
+}
+
+type nopCloser struct{ *strings.Reader }
+
+func (nopCloser) Close() error { return nil }
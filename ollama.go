@@ -364,7 +364,8 @@ func WithFormat(format interface{}) func(interface{}) {
 	}
 }
 
-// WithKeepAlive sets the keep alive duration
+// WithKeepAlive sets the keep alive duration. It accepts a Duration, a
+// duration string such as "5m", or a number of seconds.
 func WithKeepAlive(keepAlive interface{}) func(interface{}) {
 	return func(req interface{}) {
 		switch r := req.(type) {
@@ -512,6 +513,13 @@ func WithTruncate(truncate bool) func(*EmbedRequest) {
 	}
 }
 
+// WithDimensions sets the output dimensions for embed requests
+func WithDimensions(dimensions int) func(*EmbedRequest) {
+	return func(req *EmbedRequest) {
+		req.Dimensions = dimensions
+	}
+}
+
 // WithInsecure sets insecure option for pull/push requests
 func WithInsecure(insecure bool) func(interface{}) {
 	return func(req interface{}) {
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGlobalGenerate(t *testing.T) {
@@ -294,6 +296,14 @@ func TestEmbedConfigurationMethods(t *testing.T) {
 			t.Error("Expected Truncate true")
 		}
 	})
+
+	t.Run("WithDimensions", func(t *testing.T) {
+		req := &EmbedRequest{}
+		WithDimensions(256)(req)
+		if req.Dimensions != 256 {
+			t.Errorf("Expected Dimensions 256, got %d", req.Dimensions)
+		}
+	})
 }
 
 func TestPullConfigurationMethods(t *testing.T) {
@@ -342,8 +352,78 @@ func TestTypesErrorMethod(t *testing.T) {
 }
 
 func TestDurationMarshalJSON(t *testing.T) {
-	// Duration type test - skip if not available
-	t.Skip("Duration type test skipped - implementation details")
+	tests := []struct {
+		duration Duration
+		expected string
+	}{
+		{Duration{Duration: 5 * time.Minute}, `"5m0s"`},
+		{Duration{Duration: 90 * time.Second}, `"1m30s"`},
+		{KeepForever(), `-1`},
+		{Duration{Duration: -time.Hour}, `-1`},
+		{UnloadImmediately(), `0`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.duration)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed: %v", tt.duration, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Marshal(%v) = %s, expected %s", tt.duration, data, tt.expected)
+		}
+	}
+
+	req := &GenerateRequest{Model: "llama3"}
+	WithKeepAlive(KeepForever())(req)
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal request failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"keep_alive":-1`) {
+		t.Errorf("Expected keep_alive -1 in %s", data)
+	}
+
+	chat := &ChatRequest{Model: "llama3"}
+	chat.SetKeepAlive(Duration{Duration: 10 * time.Minute})
+	data, err = json.Marshal(chat)
+	if err != nil {
+		t.Fatalf("Marshal request failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"keep_alive":"10m0s"`) {
+		t.Errorf("Expected keep_alive 10m0s in %s", data)
+	}
+}
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{`"5m"`, 5 * time.Minute},
+		{`"1h30m"`, 90 * time.Minute},
+		{`300`, 5 * time.Minute},
+		{`1.5`, 1500 * time.Millisecond},
+		{`0`, 0},
+		{`-1`, -1},
+		{`"-1m"`, -1},
+	}
+
+	for _, tt := range tests {
+		var d Duration
+		if err := json.Unmarshal([]byte(tt.input), &d); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", tt.input, err)
+		}
+		if d.Duration != tt.expected {
+			t.Errorf("Unmarshal(%s) = %v, expected %v", tt.input, d.Duration, tt.expected)
+		}
+	}
+
+	for _, input := range []string{`"soon"`, `true`, `[1]`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
 }
 
 func TestExtractThinkingContent(t *testing.T) {
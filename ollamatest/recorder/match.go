@@ -0,0 +1,95 @@
+package recorder
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Matcher reports whether a recorded request matches a live request with the
+// given body.
+type Matcher func(req *http.Request, body []byte, recorded *Request) bool
+
+// DefaultMatcher matches on endpoint, model and messages.
+var DefaultMatcher = MatchAll(MatchEndpoint(), MatchModel(), MatchMessages())
+
+// MatchAll matches if all matchers match.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req *http.Request, body []byte, recorded *Request) bool {
+		for _, match := range matchers {
+			if !match(req, body, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchEndpoint matches on method and path.
+func MatchEndpoint() Matcher {
+	return func(req *http.Request, body []byte, recorded *Request) bool {
+		return req.Method == recorded.Method && req.URL.Path == recorded.Path
+	}
+}
+
+// MatchModel matches on the model named in the request body.
+func MatchModel() Matcher {
+	return func(req *http.Request, body []byte, recorded *Request) bool {
+		return bodyModel(body) == recorded.Model
+	}
+}
+
+// MatchMessages matches on a hash of the conversation: messages, prompt,
+// system prompt, suffix and embedding input. Options such as temperature are
+// ignored.
+func MatchMessages() Matcher {
+	return func(req *http.Request, body []byte, recorded *Request) bool {
+		return messagesHash(body) == recorded.MessagesHash
+	}
+}
+
+// MatchBody matches on the exact request body.
+func MatchBody() Matcher {
+	return func(req *http.Request, body []byte, recorded *Request) bool {
+		return hashBytes(body) == recorded.BodyHash
+	}
+}
+
+// bodyModel returns the model field of a JSON body.
+func bodyModel(body []byte) string {
+	var b struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &b) != nil {
+		return ""
+	}
+	return b.Model
+}
+
+// conversationFields are the body fields hashed by MatchMessages.
+var conversationFields = []string{"messages", "prompt", "system", "suffix", "input"}
+
+// messagesHash hashes the conversation fields of a JSON body, or returns ""
+// if it has none.
+func messagesHash(body []byte) string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+
+	var data []byte
+	found := false
+	for _, name := range conversationFields {
+		value, ok := fields[name]
+		if ok {
+			found = true
+		}
+		data = append(data, name...)
+		data = append(data, 0)
+		data = append(data, value...)
+		data = append(data, 0)
+	}
+	if !found {
+		return ""
+	}
+	return hashBytes(data)
+}
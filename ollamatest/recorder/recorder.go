@@ -0,0 +1,502 @@
+/*
+Package recorder records HTTP traffic between an ollama client and a server
+into a cassette file and replays it, so tests can run against fixtures that
+were recorded once against a real Ollama server.
+
+	rec, err := recorder.New("testdata/chat.json", &recorder.Options{Mode: recorder.ModeAuto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Stop()
+
+	client, _ := ollama.NewClient(ollama.WithHTTPClient(rec.Client()))
+
+Streaming responses are stored frame by frame together with the time each
+frame arrived; set Options.Realtime to replay them with the recorded pacing.
+Request headers are not recorded, so credentials never end up in a cassette.
+Blob uploads are streamed through without being recorded or buffered; they
+are matched by the digest in their path.
+*/
+package recorder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never contacts a
+	// server. It is the default.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the server and records them,
+	// replacing the cassette when the recorder is stopped.
+	ModeRecord
+	// ModeAuto replays if the cassette exists and records otherwise.
+	ModeAuto
+)
+
+// ErrNoMatch is returned in replay mode when no recorded interaction matches
+// a request.
+var ErrNoMatch = errors.New("recorder: no recorded interaction matches the request")
+
+// cassetteVersion is the version of the cassette format.
+const cassetteVersion = 1
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Model and MessagesHash are extracted from
+// the body when it is recorded so matchers can compare them cheaply.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Body is the request body if it is JSON; other bodies are only
+	// recorded by hash. Blob upload bodies are not recorded at all.
+	Body         json.RawMessage `json:"body,omitempty"`
+	BodyHash     string          `json:"body_hash"`
+	Model        string          `json:"model,omitempty"`
+	MessagesHash string          `json:"messages_hash,omitempty"`
+}
+
+// Response is a recorded response. Streaming (NDJSON) responses are stored as
+// Frames, all other responses as Body.
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Frames     []Frame           `json:"frames,omitempty"`
+}
+
+// Frame is one line of a streaming response and when it arrived, relative to
+// the start of the request.
+type Frame struct {
+	Offset time.Duration   `json:"offset"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Options configures a Recorder.
+type Options struct {
+	Mode Mode
+
+	// Transport sends requests in record mode (default
+	// http.DefaultTransport).
+	Transport http.RoundTripper
+
+	// Matcher selects the recorded interaction for a request in replay mode
+	// (default DefaultMatcher).
+	Matcher Matcher
+
+	// Realtime replays streaming frames with their recorded timing instead
+	// of all at once.
+	Realtime bool
+}
+
+// Recorder is an http.RoundTripper that records or replays a cassette.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matcher   Matcher
+	realtime  bool
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     map[*Interaction]bool
+	last     *Interaction
+}
+
+// New creates a Recorder for the cassette at path. In replay mode the
+// cassette must exist.
+func New(path string, opts *Options) (*Recorder, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Transport == nil {
+		o.Transport = http.DefaultTransport
+	}
+	if o.Matcher == nil {
+		o.Matcher = DefaultMatcher
+	}
+
+	r := &Recorder{
+		path:      path,
+		mode:      o.Mode,
+		transport: o.Transport,
+		matcher:   o.Matcher,
+		realtime:  o.Realtime,
+		cassette:  &Cassette{Version: cassetteVersion},
+		used:      make(map[*Interaction]bool),
+	}
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeReplay {
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+	}
+
+	return r, nil
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("recorder: failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("recorder: invalid cassette %s: %w", path, err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("recorder: unsupported cassette version %d", cassette.Version)
+	}
+	return &cassette, nil
+}
+
+// Mode returns the mode the recorder runs in, with ModeAuto resolved.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an http.Client that sends requests through the recorder,
+// for use with ollama.WithHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Cassette returns the interactions recorded or loaded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *r.cassette
+	copied.Interactions = append([]*Interaction(nil), r.cassette.Interactions...)
+	return &copied
+}
+
+// Stop writes the cassette in record mode. An interaction is added once its
+// response body has been read to the end or closed; a body closed early is
+// recorded as far as it was read.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("recorder: failed to encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("recorder: failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("recorder: failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if isBlobUpload(req) {
+		// Blobs can be gigabytes; pass them through instead of reading
+		// them into memory, and drop them on replay
+		if r.mode == ModeRecord {
+			return r.record(req, nil)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+	} else if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("recorder: failed to read request body: %w", err)
+		}
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	start := time.Now()
+
+	out := req.Clone(req.Context())
+	if !isBlobUpload(req) {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: newRequest(req, body),
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     recordHeader(resp.Header),
+		},
+	}
+
+	resp.Body = &recordingBody{
+		body:      resp.Body,
+		start:     start,
+		streaming: isStreaming(resp.Header),
+		response:  &interaction.Response,
+		done: func() {
+			r.mu.Lock()
+			r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+			r.mu.Unlock()
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	interaction := r.find(req, body)
+	if interaction == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoMatch, req.Method, req.URL.Path)
+	}
+
+	recorded := interaction.Response
+	header := make(http.Header)
+	for key, value := range recorded.Header {
+		header.Set(key, value)
+	}
+
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode: recorded.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Request:    req,
+	}
+
+	if recorded.Frames == nil {
+		resp.Body = io.NopCloser(strings.NewReader(recorded.Body))
+		resp.ContentLength = int64(len(recorded.Body))
+		return resp, nil
+	}
+
+	resp.ContentLength = -1
+	pr, pw := io.Pipe()
+	resp.Body = pr
+	go func() {
+		start := time.Now()
+		for _, frame := range recorded.Frames {
+			if r.realtime {
+				if wait := frame.Offset - time.Since(start); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-req.Context().Done():
+						pw.CloseWithError(req.Context().Err())
+						return
+					}
+				}
+			}
+			if _, err := pw.Write(append(append([]byte(nil), frame.Data...), '\n')); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return resp, nil
+}
+
+// find returns the first unused interaction matching the request, or the
+// most recently used one if all matches have been used.
+func (r *Recorder) find(req *http.Request, body []byte) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var fallback *Interaction
+	for _, interaction := range r.cassette.Interactions {
+		if !r.matcher(req, body, &interaction.Request) {
+			continue
+		}
+		if !r.used[interaction] {
+			r.used[interaction] = true
+			r.last = interaction
+			return interaction
+		}
+		if interaction == r.last || fallback == nil {
+			fallback = interaction
+		}
+	}
+	return fallback
+}
+
+// newRequest records the parts of a request used for matching.
+func newRequest(req *http.Request, body []byte) Request {
+	recorded := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+	if !isBlobUpload(req) {
+		recorded.BodyHash = hashBytes(body)
+	}
+	if len(body) > 0 && json.Valid(body) {
+		recorded.Body = json.RawMessage(body)
+		recorded.Model = bodyModel(body)
+		recorded.MessagesHash = messagesHash(body)
+	}
+	return recorded
+}
+
+// recordHeader keeps the response headers that matter for replay.
+func recordHeader(header http.Header) map[string]string {
+	recorded := make(map[string]string)
+	for key := range header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Date", "Set-Cookie", "Content-Length", "Transfer-Encoding":
+			continue
+		}
+		recorded[key] = header.Get(key)
+	}
+	return recorded
+}
+
+func isBlobUpload(req *http.Request) bool {
+	return strings.Contains(req.URL.Path, "/api/blobs/")
+}
+
+func isStreaming(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Type"), "ndjson")
+}
+
+// recordingBody captures a response body as it is read by the client. Close
+// may be called while a Read is in progress, so the captured state is
+// guarded by mu; the underlying body is read outside the lock so that Close
+// can interrupt a blocked Read.
+type recordingBody struct {
+	body      io.ReadCloser
+	start     time.Time
+	streaming bool
+	response  *Response
+	done      func()
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	line     []byte
+	finished bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.mu.Lock()
+		if !b.finished {
+			if b.streaming {
+				b.frames(p[:n])
+			} else {
+				b.buf.Write(p[:n])
+			}
+		}
+		b.mu.Unlock()
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.body.Close()
+	b.finish()
+	return err
+}
+
+// frames splits streamed data into frames, stamping each complete line.
+func (b *recordingBody) frames(data []byte) {
+	b.line = append(b.line, data...)
+	for {
+		i := bytes.IndexByte(b.line, '\n')
+		if i < 0 {
+			return
+		}
+		b.addFrame(b.line[:i])
+		b.line = b.line[i+1:]
+	}
+}
+
+func (b *recordingBody) addFrame(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	b.response.Frames = append(b.response.Frames, Frame{
+		Offset: time.Since(b.start),
+		Data:   json.RawMessage(append([]byte(nil), line...)),
+	})
+}
+
+func (b *recordingBody) finish() {
+	b.mu.Lock()
+	if b.finished {
+		b.mu.Unlock()
+		return
+	}
+	b.finished = true
+	if b.streaming {
+		b.addFrame(b.line)
+		b.line = nil
+		if b.response.Frames == nil {
+			b.response.Frames = []Frame{}
+		}
+	} else {
+		b.response.Body = b.buf.String()
+	}
+	b.mu.Unlock()
+
+	b.done()
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
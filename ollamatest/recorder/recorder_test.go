@@ -0,0 +1,275 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/generate":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for _, word := range []string{"The", " sky", " is", " blue"} {
+				fmt.Fprintf(w, `{"model":"llama3","response":%q,"done":false}`+"\n", word)
+				w.(http.Flusher).Flush()
+				time.Sleep(15 * time.Millisecond)
+			}
+			fmt.Fprintln(w, `{"model":"llama3","response":"","done":true,"eval_count":4}`)
+		case "/api/chat":
+			fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"Hello!"},"done":true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model 'missing' not found"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func collect(t *testing.T, client *ollama.Client) string {
+	t.Helper()
+	responseChan, errorChan := client.GenerateStream(context.Background(), &ollama.GenerateRequest{Model: "llama3", Prompt: "Why is the sky blue?"})
+	var text strings.Builder
+	for resp := range responseChan {
+		text.WriteString(resp.Response)
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	return text.String()
+}
+
+func record(t *testing.T, path string) {
+	t.Helper()
+	upstream := newUpstream(t)
+
+	rec, err := New(path, &Options{Mode: ModeRecord})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	client, _ := ollama.NewClient(
+		ollama.WithHost(upstream.URL),
+		ollama.WithHTTPClient(rec.Client()),
+		ollama.WithHeaders(map[string]string{"Authorization": "Bearer secret"}),
+	)
+
+	if text := collect(t, client); text != "The sky is blue" {
+		t.Fatalf("Unexpected stream %q", text)
+	}
+	chat, err := client.Chat(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil || chat.Message.Content != "Hello!" {
+		t.Fatalf("Chat failed: %v %+v", err, chat)
+	}
+	if _, err := client.Show(context.Background(), &ollama.ShowRequest{Model: "missing"}); err == nil {
+		t.Fatal("Expected Show to fail")
+	}
+
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+	record(t, path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Cassette not written: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("Expected request headers not to be recorded")
+	}
+
+	cassette, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("Expected 3 interactions, got %d", len(cassette.Interactions))
+	}
+
+	stream := cassette.Interactions[0]
+	if stream.Request.Path != "/api/generate" || stream.Request.Model != "llama3" || stream.Request.MessagesHash == "" {
+		t.Errorf("Unexpected request: %+v", stream.Request)
+	}
+	if len(stream.Response.Frames) != 5 {
+		t.Fatalf("Expected 5 frames, got %d", len(stream.Response.Frames))
+	}
+	for i := 1; i < len(stream.Response.Frames); i++ {
+		if stream.Response.Frames[i].Offset < stream.Response.Frames[i-1].Offset {
+			t.Error("Expected frame offsets to increase")
+		}
+	}
+	if stream.Response.Frames[4].Offset < 45*time.Millisecond {
+		t.Errorf("Expected recorded timing, got %v", stream.Response.Frames[4].Offset)
+	}
+
+	if cassette.Interactions[2].Response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 to be recorded, got %d", cassette.Interactions[2].Response.StatusCode)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	record(t, path)
+
+	rec, err := New(path, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	client, _ := ollama.NewClient(ollama.WithHost("http://ollama.invalid:11434"), ollama.WithHTTPClient(rec.Client()))
+
+	start := time.Now()
+	if text := collect(t, client); text != "The sky is blue" {
+		t.Errorf("Unexpected replayed stream %q", text)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected replay without delays, took %v", elapsed)
+	}
+
+	chat, err := client.Chat(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil || chat.Message.Content != "Hello!" {
+		t.Errorf("Unexpected replayed chat: %v %+v", err, chat)
+	}
+
+	_, err = client.Show(context.Background(), &ollama.ShowRequest{Model: "missing"})
+	var respErr *ollama.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected replayed 404, got %v", err)
+	}
+
+	// Repeated requests reuse the last match
+	if text := collect(t, client); text != "The sky is blue" {
+		t.Errorf("Unexpected repeated stream %q", text)
+	}
+
+	_, err = client.Chat(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "Something else"}},
+	})
+	if !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Expected ErrNoMatch for different messages, got %v", err)
+	}
+}
+
+func TestReplayRealtimeAndMatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	record(t, path)
+
+	rec, err := New(path, &Options{Mode: ModeAuto, Realtime: true, Matcher: MatchEndpoint()})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if rec.Mode() != ModeReplay {
+		t.Fatalf("Expected auto mode to replay an existing cassette")
+	}
+	client, _ := ollama.NewClient(ollama.WithHTTPClient(rec.Client()))
+
+	start := time.Now()
+	collect(t, client)
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("Expected realtime replay, took %v", elapsed)
+	}
+
+	chat, err := client.Chat(context.Background(), &ollama.ChatRequest{Model: "other", Messages: []ollama.Message{{Role: "user", Content: "Anything"}}})
+	if err != nil || chat.Message.Content != "Hello!" {
+		t.Errorf("Expected endpoint matcher to ignore model and messages: %v", err)
+	}
+}
+
+func TestBlobUploadPassesThrough(t *testing.T) {
+	data := bytes.Repeat([]byte("gguf"), 1000)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !bytes.Equal(body, data) {
+			t.Errorf("Expected the blob to be forwarded, got %d bytes", len(body))
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := New(path, &Options{Mode: ModeRecord})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	client, _ := ollama.NewClient(ollama.WithHost(upstream.URL), ollama.WithHTTPClient(rec.Client()))
+
+	digest, err := client.CreateBlobFrom(context.Background(), bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("CreateBlobFrom failed: %v", err)
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	cassette := rec.Cassette()
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("Expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+	upload := cassette.Interactions[1].Request
+	if upload.Path != "/api/blobs/"+digest || upload.Body != nil || upload.BodyHash != "" {
+		t.Errorf("Expected the upload body not to be recorded: %+v", upload)
+	}
+
+	rec, err = New(path, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	client, _ = ollama.NewClient(ollama.WithHost("http://ollama.invalid:11434"), ollama.WithHTTPClient(rec.Client()))
+	if _, err := client.CreateBlobFrom(context.Background(), bytes.NewReader(data), int64(len(data)), nil); err != nil {
+		t.Errorf("Replayed CreateBlobFrom failed: %v", err)
+	}
+}
+
+func TestModeAutoRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.json")
+	rec, err := New(path, &Options{Mode: ModeAuto})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if rec.Mode() != ModeRecord {
+		t.Error("Expected auto mode to record a missing cassette")
+	}
+
+	if _, err := New(path, nil); err == nil {
+		t.Error("Expected replay of a missing cassette to fail")
+	}
+}
+
+func TestMessagesHash(t *testing.T) {
+	a := messagesHash([]byte(`{"model":"a","prompt":"hi","options":{"temperature":1}}`))
+	b := messagesHash([]byte(`{"model":"b","prompt":"hi","options":{"temperature":0}}`))
+	c := messagesHash([]byte(`{"model":"a","prompt":"hello"}`))
+	if a == "" || a != b || a == c {
+		t.Errorf("Unexpected hashes: %q %q %q", a, b, c)
+	}
+	if messagesHash([]byte(`{"model":"a"}`)) != "" {
+		t.Error("Expected no hash without conversation fields")
+	}
+}
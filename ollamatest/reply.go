@@ -0,0 +1,484 @@
+package ollamatest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+// Reply scripts the response to a chat or generate request.
+type Reply struct {
+	// Content is the generated text.
+	Content string
+	// Thinking is sent before the content.
+	Thinking string
+	// ToolCalls are sent in their own frame after the content.
+	ToolCalls []ollama.ToolCall
+
+	// Chunks sets the streamed pieces explicitly; otherwise Content is split
+	// into chunks of ChunkSize runes, or into words if ChunkSize is 0.
+	Chunks    []string
+	ChunkSize int
+	// InitialDelay is waited before the first frame, ChunkDelay between
+	// frames. Non-streaming replies wait for the sum.
+	InitialDelay time.Duration
+	ChunkDelay   time.Duration
+
+	// DoneReason defaults to "stop".
+	DoneReason string
+	// PromptEvalCount defaults to the number of words in the prompt,
+	// EvalCount to the number of chunks.
+	PromptEvalCount int
+	EvalCount       int
+
+	// Error fails the request with Status (default 500) before anything is
+	// sent.
+	Error  string
+	Status int
+	// StreamError is sent as an error frame after ErrorAfter chunks of a
+	// streaming reply, ending the stream. Non-streaming requests fail with
+	// status 500 instead.
+	StreamError string
+	ErrorAfter  int
+}
+
+// SetReply sets the reply used for chat and generate requests when no reply
+// is queued and no handler is set.
+func (s *Server) SetReply(r Reply) {
+	s.mu.Lock()
+	s.reply = &r
+	s.mu.Unlock()
+}
+
+// EnqueueReply queues replies that are used, in order, by the next chat and
+// generate requests before any handler or fixed reply.
+func (s *Server) EnqueueReply(replies ...Reply) {
+	s.mu.Lock()
+	s.replies = append(s.replies, replies...)
+	s.mu.Unlock()
+}
+
+// HandleChat sets a function that generates chat replies.
+func (s *Server) HandleChat(fn func(*ollama.ChatRequest) Reply) {
+	s.mu.Lock()
+	s.chat = fn
+	s.mu.Unlock()
+}
+
+// HandleGenerate sets a function that generates generate replies.
+func (s *Server) HandleGenerate(fn func(*ollama.GenerateRequest) Reply) {
+	s.mu.Lock()
+	s.generate = fn
+	s.mu.Unlock()
+}
+
+// nextReply picks the reply for a request; fallback produces the echo reply.
+func (s *Server) nextReply(handler func() (Reply, bool), fallback func() Reply) Reply {
+	s.mu.Lock()
+	if len(s.replies) > 0 {
+		r := s.replies[0]
+		s.replies = s.replies[1:]
+		s.mu.Unlock()
+		return r
+	}
+	fixed := s.reply
+	s.mu.Unlock()
+
+	if r, ok := handler(); ok {
+		return r
+	}
+	if fixed != nil {
+		return *fixed
+	}
+	return fallback()
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req ollama.ChatRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		s.writeLoad(w, m, req.KeepAlive, func(reason string) interface{} {
+			return ollama.ChatResponse{Model: m.Name, CreatedAt: now(), Message: ollama.Message{Role: "assistant"}, Done: true, DoneReason: reason}
+		})
+		return
+	}
+	if msg := unsupported(m, "chat", len(req.Tools) > 0, req.Think, ""); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	reply := s.nextReply(func() (Reply, bool) {
+		s.mu.Lock()
+		fn := s.chat
+		s.mu.Unlock()
+		if fn == nil {
+			return Reply{}, false
+		}
+		return fn(&req), true
+	}, func() Reply {
+		return Reply{Content: req.Messages[len(req.Messages)-1].Content}
+	})
+
+	var words int
+	for _, msg := range req.Messages {
+		words += len(strings.Fields(msg.Content))
+	}
+	s.touch(m, req.KeepAlive)
+
+	s.writeReply(w, r.Context(), reply, req.Stream, words, func(f frame) interface{} {
+		resp := ollama.ChatResponse{
+			Model:     m.Name,
+			CreatedAt: now(),
+			Message:   ollama.Message{Role: "assistant", Content: f.content, Thinking: f.thinking, ToolCalls: f.toolCalls},
+		}
+		if d := f.done; d != nil {
+			resp.Done, resp.DoneReason, resp.TotalDuration = true, d.reason, int64(d.total)
+			resp.PromptEvalCount, resp.PromptEvalDuration = d.promptEvalCount, int64(d.promptEvalDuration)
+			resp.EvalCount, resp.EvalDuration = d.evalCount, int64(d.evalDuration)
+		}
+		return resp
+	})
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req ollama.GenerateRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	if req.Prompt == "" && req.Suffix == "" {
+		s.writeLoad(w, m, req.KeepAlive, func(reason string) interface{} {
+			return ollama.GenerateResponse{Model: m.Name, CreatedAt: now(), Done: true, DoneReason: reason}
+		})
+		return
+	}
+	if msg := unsupported(m, "generate", false, req.Think, req.Suffix); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	reply := s.nextReply(func() (Reply, bool) {
+		s.mu.Lock()
+		fn := s.generate
+		s.mu.Unlock()
+		if fn == nil {
+			return Reply{}, false
+		}
+		return fn(&req), true
+	}, func() Reply {
+		return Reply{Content: req.Prompt}
+	})
+
+	s.touch(m, req.KeepAlive)
+
+	s.writeReply(w, r.Context(), reply, req.Stream, len(strings.Fields(req.System+" "+req.Prompt)), func(f frame) interface{} {
+		resp := ollama.GenerateResponse{
+			Model:     m.Name,
+			CreatedAt: now(),
+			Response:  f.content,
+			Thinking:  f.thinking,
+		}
+		if d := f.done; d != nil {
+			resp.Done, resp.DoneReason, resp.TotalDuration = true, d.reason, int64(d.total)
+			resp.PromptEvalCount, resp.PromptEvalDuration = d.promptEvalCount, int64(d.promptEvalDuration)
+			resp.EvalCount, resp.EvalDuration = d.evalCount, int64(d.evalDuration)
+		}
+		return resp
+	})
+}
+
+// unsupported returns the server's error message if the request needs a
+// capability the model lacks.
+func unsupported(m *Model, operation string, tools bool, think *bool, suffix string) string {
+	has := func(c ollama.Capability) bool {
+		for _, capability := range m.Capabilities {
+			if capability == string(c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case !has(ollama.CapabilityCompletion):
+		return fmt.Sprintf("%q does not support %s", m.Name, operation)
+	case tools && !has(ollama.CapabilityTools):
+		return fmt.Sprintf("%q does not support tools", m.Name)
+	case think != nil && *think && !has(ollama.CapabilityThinking):
+		return fmt.Sprintf("%q does not support thinking", m.Name)
+	case suffix != "" && !has(ollama.CapabilityInsert):
+		return fmt.Sprintf("%q does not support insert", m.Name)
+	}
+	return ""
+}
+
+// writeLoad answers an empty request, which only loads or unloads the model.
+func (s *Server) writeLoad(w http.ResponseWriter, m *Model, keepAlive interface{}, response func(reason string) interface{}) {
+	s.touch(m, keepAlive)
+
+	reason := "load"
+	s.mu.Lock()
+	if _, loaded := s.loaded[modelKey(m.Name)]; !loaded {
+		reason = "unload"
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response(reason))
+}
+
+// frame is one frame of a reply; done is set on the last one.
+type frame struct {
+	content   string
+	thinking  string
+	toolCalls []ollama.ToolCall
+	done      *doneInfo
+}
+
+// doneInfo holds the final frame's stats. Eval durations are derived from
+// the counts (1ms per prompt token, 10ms per generated token) so token rates
+// are predictable.
+type doneInfo struct {
+	reason             string
+	total              time.Duration
+	promptEvalCount    int
+	promptEvalDuration time.Duration
+	evalCount          int
+	evalDuration       time.Duration
+}
+
+// writeReply writes a reply as a stream of frames built by build, or as a
+// single response with everything merged when streaming is off.
+func (s *Server) writeReply(w http.ResponseWriter, ctx context.Context, reply Reply, stream *bool, promptWords int, build func(frame) interface{}) {
+	start := time.Now()
+
+	if reply.Error != "" {
+		status := reply.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeError(w, status, reply.Error)
+		return
+	}
+
+	chunks := reply.Chunks
+	if chunks == nil {
+		chunks = splitContent(reply.Content, reply.ChunkSize)
+	}
+
+	done := &doneInfo{
+		reason:          reply.DoneReason,
+		promptEvalCount: reply.PromptEvalCount,
+		evalCount:       reply.EvalCount,
+	}
+	if done.reason == "" {
+		done.reason = "stop"
+	}
+	if done.promptEvalCount == 0 {
+		done.promptEvalCount = promptWords
+	}
+	if done.evalCount == 0 {
+		done.evalCount = len(chunks)
+		if done.evalCount == 0 && len(reply.ToolCalls) > 0 {
+			done.evalCount = 1
+		}
+	}
+	done.promptEvalDuration = time.Duration(done.promptEvalCount) * time.Millisecond
+	done.evalDuration = time.Duration(done.evalCount) * 10 * time.Millisecond
+
+	sleep := func(d time.Duration) bool {
+		if d <= 0 {
+			return true
+		}
+		select {
+		case <-time.After(d):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if stream != nil && !*stream {
+		if !sleep(reply.InitialDelay + time.Duration(len(chunks))*reply.ChunkDelay) {
+			return
+		}
+		if reply.StreamError != "" {
+			writeError(w, http.StatusInternalServerError, reply.StreamError)
+			return
+		}
+		done.total = time.Since(start)
+		writeJSON(w, http.StatusOK, build(frame{
+			content:   strings.Join(chunks, ""),
+			thinking:  reply.Thinking,
+			toolCalls: reply.ToolCalls,
+			done:      done,
+		}))
+		return
+	}
+
+	sw := newStreamWriter(w)
+	if !sleep(reply.InitialDelay) {
+		return
+	}
+	if reply.Thinking != "" {
+		sw.write(build(frame{thinking: reply.Thinking}))
+	}
+	for i, chunk := range chunks {
+		if reply.StreamError != "" && i == reply.ErrorAfter {
+			break
+		}
+		if i > 0 && !sleep(reply.ChunkDelay) {
+			return
+		}
+		sw.write(build(frame{content: chunk}))
+	}
+	if reply.StreamError != "" {
+		sw.write(map[string]string{"error": reply.StreamError})
+		return
+	}
+	if len(reply.ToolCalls) > 0 {
+		sw.write(build(frame{toolCalls: reply.ToolCalls}))
+	}
+	done.total = time.Since(start)
+	sw.write(build(frame{done: done}))
+}
+
+// splitContent splits text into chunks of size runes, or into words (each
+// keeping its leading space) if size is 0.
+func splitContent(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	if size > 0 {
+		for len(text) > 0 {
+			n, i := 0, 0
+			for i < len(text) && n < size {
+				_, w := utf8.DecodeRuneInString(text[i:])
+				i += w
+				n++
+			}
+			chunks = append(chunks, text[:i])
+			text = text[i:]
+		}
+		return chunks
+	}
+
+	start := 0
+	for i := 1; i < len(text); i++ {
+		if text[i] == ' ' && text[i-1] != ' ' {
+			chunks = append(chunks, text[start:i])
+			start = i
+		}
+	}
+	return append(chunks, text[start:])
+}
+
+func (s *Server) handleEmbed(w http.ResponseWriter, body []byte) {
+	var req ollama.EmbedRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	var inputs []string
+	switch input := req.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []interface{}:
+		for _, v := range input {
+			text, ok := v.(string)
+			if !ok {
+				writeError(w, http.StatusBadRequest, "invalid input type")
+				return
+			}
+			inputs = append(inputs, text)
+		}
+	case nil:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid input type")
+		return
+	}
+
+	s.touch(m, req.KeepAlive)
+
+	resp := ollama.EmbedResponse{Model: m.Name, Embeddings: [][]float64{}}
+	for _, input := range inputs {
+		embedding := FakeEmbedding(m.Name, input, m.EmbeddingLength)
+		if req.Dimensions > 0 && req.Dimensions < len(embedding) {
+			embedding = ollama.TruncateEmbedding(embedding, req.Dimensions)
+		}
+		resp.Embeddings = append(resp.Embeddings, embedding)
+		resp.PromptEvalCount += len(strings.Fields(input))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, body []byte) {
+	var req ollama.EmbeddingsRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	s.touch(m, req.KeepAlive)
+	writeJSON(w, http.StatusOK, ollama.EmbeddingsResponse{Embedding: FakeEmbedding(m.Name, req.Prompt, m.EmbeddingLength)})
+}
+
+// FakeEmbedding returns the embedding the server produces for an input: a
+// unit vector of the given length derived from a hash of model and input.
+// The same input always gives the same vector.
+func FakeEmbedding(model, input string, dimensions int) []float64 {
+	seed := sha256.Sum256([]byte(displayName(model) + "\x00" + input))
+
+	embedding := make([]float64, dimensions)
+	var block [sha256.Size]byte
+	var sum float64
+	for i := range embedding {
+		if i%4 == 0 {
+			var counter [4]byte
+			binary.BigEndian.PutUint32(counter[:], uint32(i/4))
+			block = sha256.Sum256(append(seed[:], counter[:]...))
+		}
+		bits := binary.BigEndian.Uint64(block[(i%4)*8:])
+		embedding[i] = float64(bits)/math.MaxUint64*2 - 1
+		sum += embedding[i] * embedding[i]
+	}
+
+	if sum > 0 {
+		norm := math.Sqrt(sum)
+		for i := range embedding {
+			embedding[i] /= norm
+		}
+	}
+	return embedding
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,694 @@
+/*
+Package ollamatest provides an in-process fake Ollama server for testing code
+that uses the ollama client.
+
+The server implements the endpoints the client uses, keeps an in-memory model
+list, records every request and lets tests script the responses:
+
+	srv := ollamatest.NewServer()
+	defer srv.Close()
+
+	srv.AddModel(ollamatest.Model{Name: "llama3"})
+	srv.SetReply(ollamatest.Reply{Content: "The sky is blue.", ChunkDelay: 10 * time.Millisecond})
+
+	client := srv.Client()
+	resp, err := client.Chat(ctx, &ollama.ChatRequest{Model: "llama3", Messages: msgs})
+
+Chat and generate reply with the queued replies first (EnqueueReply), then
+the handler (HandleChat, HandleGenerate), then the fixed reply (SetReply), and
+otherwise echo the last message. Embeddings are deterministic fake vectors,
+see FakeEmbedding.
+*/
+package ollamatest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+// DefaultVersion is the version reported by /api/version.
+const DefaultVersion = "0.0.0-ollamatest"
+
+// DefaultKeepAlive is how long a model stays in /api/ps after a request
+// without keep_alive.
+const DefaultKeepAlive = 5 * time.Minute
+
+// Model is a model known to the server.
+type Model struct {
+	// Name, e.g. "llama3" or "llama3:8b". A missing tag means "latest".
+	Name string
+	// Digest defaults to a hash of the name.
+	Digest string
+	// Size defaults to 1 GiB.
+	Size int64
+	// Capabilities default to ["completion"].
+	Capabilities []string
+	Details      ollama.ModelDetails
+	ModelInfo    map[string]interface{}
+	Template     string
+	Parameters   string
+	License      string
+	System       string
+	// EmbeddingLength is the length of fake embeddings (default 16).
+	EmbeddingLength int
+	ModifiedAt      time.Time
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+}
+
+// Decode unmarshals the JSON request body into v.
+func (r Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Model returns the model named in the request body, or "".
+func (r Request) Model() string {
+	var body struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(r.Body, &body)
+	return body.Model
+}
+
+type failure struct {
+	status  int
+	message string
+}
+
+type loadedModel struct {
+	model     *Model
+	expiresAt time.Time
+}
+
+// Server is a fake Ollama server. Its methods are safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, for ollama.WithHost.
+	URL string
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	version  string
+	models   map[string]*Model
+	loaded   map[string]loadedModel
+	blobs    map[string]int64
+	requests []Request
+	failures map[string][]failure
+	replies  []Reply
+	reply    *Reply
+	chat     func(*ollama.ChatRequest) Reply
+	generate func(*ollama.GenerateRequest) Reply
+}
+
+// NewServer starts a fake server with no models.
+func NewServer() *Server {
+	s := &Server{
+		version:  DefaultVersion,
+		models:   make(map[string]*Model),
+		loaded:   make(map[string]loadedModel),
+		blobs:    make(map[string]int64),
+		failures: make(map[string][]failure),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns a client for the server. Options are applied after the
+// host is set.
+func (s *Server) Client(options ...ollama.ClientOption) *ollama.Client {
+	client, _ := ollama.NewClient(append([]ollama.ClientOption{ollama.WithHost(s.URL)}, options...)...)
+	return client
+}
+
+// SetVersion sets the version reported by /api/version.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	s.version = version
+	s.mu.Unlock()
+}
+
+// AddModel adds or replaces a model.
+func (s *Server) AddModel(m Model) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addModel(m)
+}
+
+func (s *Server) addModel(m Model) *Model {
+	key := modelKey(m.Name)
+	m.Name = displayName(m.Name)
+	if m.Digest == "" {
+		sum := sha256.Sum256([]byte(key))
+		m.Digest = hex.EncodeToString(sum[:])
+	}
+	if m.Size == 0 {
+		m.Size = 1 << 30
+	}
+	if m.Capabilities == nil {
+		m.Capabilities = []string{string(ollama.CapabilityCompletion)}
+	}
+	if m.EmbeddingLength == 0 {
+		m.EmbeddingLength = 16
+	}
+	if m.ModifiedAt.IsZero() {
+		m.ModifiedAt = time.Now()
+	}
+	if m.Details.Format == "" {
+		m.Details.Format = "gguf"
+	}
+
+	s.models[key] = &m
+	return &m
+}
+
+// RemoveModel removes a model, reporting whether it existed.
+func (s *Server) RemoveModel(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := modelKey(name)
+	_, ok := s.models[key]
+	delete(s.models, key)
+	delete(s.loaded, key)
+	return ok
+}
+
+// Models returns the names of all models, sorted.
+func (s *Server) Models() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.models))
+	for _, m := range s.models {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadModel marks a model as running until expiresAt, as shown by /api/ps.
+func (s *Server) LoadModel(name string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.models[modelKey(name)]; ok {
+		s.loaded[modelKey(name)] = loadedModel{model: m, expiresAt: expiresAt}
+	}
+}
+
+// Requests returns all requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests received for a path, e.g. "/api/chat".
+func (s *Server) RequestsTo(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []Request
+	for _, r := range s.requests {
+		if r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// LastRequest returns the most recent request, if any.
+func (s *Server) LastRequest() (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requests) == 0 {
+		return Request{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// Reset forgets recorded requests, queued replies and pending failures.
+// Models are kept.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.replies = nil
+	s.failures = make(map[string][]failure)
+}
+
+// FailNext makes the next request to path fail with the given HTTP status
+// and error message. Calls queue up failures for consecutive requests.
+func (s *Server) FailNext(path string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure{status: status, message: message})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+		Time:   time.Now(),
+	})
+
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/api/blobs/") {
+		path = "/api/blobs"
+	}
+	var fail *failure
+	for _, key := range []string{r.URL.Path, path} {
+		if queued := s.failures[key]; len(queued) > 0 {
+			fail = &queued[0]
+			s.failures[key] = queued[1:]
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if fail != nil {
+		writeError(w, fail.status, fail.message)
+		return
+	}
+
+	switch {
+	case path == "/api/generate" && r.Method == http.MethodPost:
+		s.handleGenerate(w, r, body)
+	case path == "/api/chat" && r.Method == http.MethodPost:
+		s.handleChat(w, r, body)
+	case path == "/api/embed" && r.Method == http.MethodPost:
+		s.handleEmbed(w, body)
+	case path == "/api/embeddings" && r.Method == http.MethodPost:
+		s.handleEmbeddings(w, body)
+	case path == "/api/tags" && r.Method == http.MethodGet:
+		s.handleTags(w)
+	case path == "/api/show" && r.Method == http.MethodPost:
+		s.handleShow(w, body)
+	case path == "/api/pull" && r.Method == http.MethodPost:
+		s.handlePull(w, body)
+	case path == "/api/push" && r.Method == http.MethodPost:
+		s.handlePush(w, body)
+	case path == "/api/create" && r.Method == http.MethodPost:
+		s.handleCreate(w, body)
+	case path == "/api/copy" && r.Method == http.MethodPost:
+		s.handleCopy(w, body)
+	case path == "/api/delete" && r.Method == http.MethodDelete:
+		s.handleDelete(w, body)
+	case path == "/api/ps" && r.Method == http.MethodGet:
+		s.handlePs(w)
+	case path == "/api/version" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		version := s.version
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, ollama.VersionResponse{Version: version})
+	case path == "/api/blobs" && (r.Method == http.MethodHead || r.Method == http.MethodPost):
+		s.handleBlob(w, r.Method, strings.TrimPrefix(r.URL.Path, "/api/blobs/"), body)
+	default:
+		writeError(w, http.StatusNotFound, "404 page not found")
+	}
+}
+
+// lookup returns the model with the given name, writing a 404 if it does not
+// exist.
+func (s *Server) lookup(w http.ResponseWriter, name string) (*Model, bool) {
+	s.mu.Lock()
+	m, ok := s.models[modelKey(name)]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", name))
+	}
+	return m, ok
+}
+
+// touch marks a model as running after a request with the given keep_alive.
+func (s *Server) touch(m *Model, keepAlive interface{}) {
+	d := DefaultKeepAlive
+	if keepAlive != nil {
+		raw, _ := json.Marshal(keepAlive)
+		var parsed ollama.Duration
+		if json.Unmarshal(raw, &parsed) == nil {
+			d = parsed.Duration
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := modelKey(m.Name)
+	switch {
+	case d == 0:
+		delete(s.loaded, key)
+	case d < 0:
+		s.loaded[key] = loadedModel{model: m, expiresAt: time.Now().AddDate(100, 0, 0)}
+	default:
+		s.loaded[key] = loadedModel{model: m, expiresAt: time.Now().Add(d)}
+	}
+}
+
+func (s *Server) handleTags(w http.ResponseWriter) {
+	s.mu.Lock()
+	resp := ollama.ListResponse{Models: []ollama.ModelInfo{}}
+	for _, m := range s.models {
+		modified := m.ModifiedAt
+		details := m.Details
+		resp.Models = append(resp.Models, ollama.ModelInfo{
+			Model:      m.Name,
+			ModifiedAt: &modified,
+			Digest:     m.Digest,
+			Size:       m.Size,
+			Details:    &details,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(resp.Models, func(i, j int) bool { return resp.Models[i].Model < resp.Models[j].Model })
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleShow(w http.ResponseWriter, body []byte) {
+	var req ollama.ShowRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	modified := m.ModifiedAt
+	details := m.Details
+	writeJSON(w, http.StatusOK, ollama.ShowResponse{
+		ModifiedAt:   &modified,
+		Template:     m.Template,
+		Modelfile:    fmt.Sprintf("FROM %s\n", m.Name),
+		License:      m.License,
+		Details:      &details,
+		ModelInfo:    m.ModelInfo,
+		Parameters:   m.Parameters,
+		Capabilities: m.Capabilities,
+	})
+}
+
+func (s *Server) handlePs(w http.ResponseWriter) {
+	now := time.Now()
+
+	s.mu.Lock()
+	resp := ollama.ProcessResponse{Models: []ollama.ProcessModel{}}
+	for key, l := range s.loaded {
+		if now.After(l.expiresAt) {
+			delete(s.loaded, key)
+			continue
+		}
+		expires := l.expiresAt
+		details := l.model.Details
+		resp.Models = append(resp.Models, ollama.ProcessModel{
+			Model:     l.model.Name,
+			Name:      l.model.Name,
+			Digest:    l.model.Digest,
+			ExpiresAt: &expires,
+			Size:      l.model.Size,
+			SizeVRAM:  l.model.Size,
+			Details:   &details,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(resp.Models, func(i, j int) bool { return resp.Models[i].Model < resp.Models[j].Model })
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlePull(w http.ResponseWriter, body []byte) {
+	var req ollama.PullRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	m, ok := s.models[modelKey(req.Model)]
+	if !ok {
+		m = s.addModel(Model{Name: req.Model})
+	}
+	digest, size := m.Digest, m.Size
+	s.mu.Unlock()
+
+	layer := "sha256:" + digest
+	writeProgress(w, req.Stream, []ollama.ProgressResponse{
+		{Status: "pulling manifest"},
+		{Status: "pulling " + shortDigest(digest), Digest: layer, Total: size},
+		{Status: "pulling " + shortDigest(digest), Digest: layer, Total: size, Completed: size / 2},
+		{Status: "pulling " + shortDigest(digest), Digest: layer, Total: size, Completed: size},
+		{Status: "verifying sha256 digest"},
+		{Status: "writing manifest"},
+		{Status: "success"},
+	})
+}
+
+func (s *Server) handlePush(w http.ResponseWriter, body []byte) {
+	var req ollama.PushRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Model)
+	if !ok {
+		return
+	}
+
+	layer := "sha256:" + m.Digest
+	writeProgress(w, req.Stream, []ollama.ProgressResponse{
+		{Status: "retrieving manifest"},
+		{Status: "pushing " + shortDigest(m.Digest), Digest: layer, Total: m.Size, Completed: m.Size},
+		{Status: "pushing manifest"},
+		{Status: "success"},
+	})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, body []byte) {
+	var req ollama.CreateRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	created := Model{Name: req.Model, Template: req.Template, System: req.System}
+	if license, ok := req.License.(string); ok {
+		created.License = license
+	}
+
+	s.mu.Lock()
+	if req.From != "" {
+		base, ok := s.models[modelKey(req.From)]
+		if !ok {
+			s.mu.Unlock()
+			writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found", req.From))
+			return
+		}
+		created = *base
+		created.Name, created.Digest, created.ModifiedAt = req.Model, "", time.Time{}
+		if req.Template != "" {
+			created.Template = req.Template
+		}
+		if req.System != "" {
+			created.System = req.System
+		}
+	}
+	for name, digest := range req.Files {
+		if _, ok := s.blobs[strings.TrimPrefix(digest, "sha256:")]; !ok {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("missing blob %s for %s", digest, name))
+			return
+		}
+	}
+	if req.Parameters != nil {
+		created.Parameters = ollama.FormatParameters(req.Parameters)
+	}
+	s.addModel(created)
+	s.mu.Unlock()
+
+	writeProgress(w, req.Stream, []ollama.ProgressResponse{
+		{Status: "reading model metadata"},
+		{Status: "writing manifest"},
+		{Status: "success"},
+	})
+}
+
+func (s *Server) handleCopy(w http.ResponseWriter, body []byte) {
+	var req ollama.CopyRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	m, ok := s.lookup(w, req.Source)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	copied := *m
+	copied.Name, copied.ModifiedAt = req.Destination, time.Time{}
+	s.addModel(copied)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, body []byte) {
+	var req ollama.DeleteRequest
+	if !decode(w, body, &req) {
+		return
+	}
+	if !s.RemoveModel(req.Model) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found", req.Model))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleBlob(w http.ResponseWriter, method, digest string, body []byte) {
+	digest = strings.TrimPrefix(digest, "sha256:")
+
+	if method == http.MethodHead {
+		s.mu.Lock()
+		_, ok := s.blobs[digest]
+		s.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != digest {
+		writeError(w, http.StatusBadRequest, "digest mismatch")
+		return
+	}
+
+	s.mu.Lock()
+	s.blobs[digest] = int64(len(body))
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HasBlob reports whether a blob with the digest ("sha256:..." or bare hex)
+// has been uploaded.
+func (s *Server) HasBlob(digest string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blobs[strings.TrimPrefix(digest, "sha256:")]
+	return ok
+}
+
+// modelKey is the canonical map key for a model name.
+func modelKey(name string) string {
+	if ref, err := ollama.ParseModelRef(name); err == nil {
+		return ref.String()
+	}
+	return strings.ToLower(name)
+}
+
+// displayName is the name shown in listings, e.g. "llama3:latest".
+func displayName(name string) string {
+	if ref, err := ollama.ParseModelRef(name); err == nil {
+		return ref.ShortString()
+	}
+	return name
+}
+
+// shortDigest is the digest prefix shown in progress statuses, as the server
+// does. Custom digests may be shorter than that.
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func decode(w http.ResponseWriter, body []byte, v interface{}) bool {
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeProgress writes progress updates as a stream, or only the last one if
+// streaming is off.
+func writeProgress(w http.ResponseWriter, stream *bool, updates []ollama.ProgressResponse) {
+	if stream != nil && !*stream {
+		writeJSON(w, http.StatusOK, updates[len(updates)-1])
+		return
+	}
+
+	sw := newStreamWriter(w)
+	for _, update := range updates {
+		sw.write(update)
+	}
+}
+
+// streamWriter writes NDJSON frames, flushing after each.
+type streamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{w: w, flusher: flusher}
+}
+
+func (sw *streamWriter) write(v interface{}) {
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(v)
+	_, _ = sw.w.Write(buf.Bytes())
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+}
@@ -0,0 +1,323 @@
+package ollamatest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+)
+
+func TestChatReplies(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddModel(Model{Name: "llama3", Capabilities: []string{"completion", "tools"}})
+	client := srv.Client()
+	ctx := context.Background()
+
+	// Echo by default
+	resp, err := client.Chat(ctx, &ollama.ChatRequest{Model: "llama3", Messages: []ollama.Message{{Role: "user", Content: "Hello there"}}})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Message.Content != "Hello there" || !resp.Done || resp.DoneReason != "stop" || resp.Model != "llama3:latest" {
+		t.Errorf("Unexpected echo response: %+v", resp)
+	}
+	if resp.PromptEvalCount != 2 || resp.EvalCount != 2 {
+		t.Errorf("Unexpected counts: %d %d", resp.PromptEvalCount, resp.EvalCount)
+	}
+
+	// Queued replies come before the handler and the fixed reply
+	srv.SetReply(Reply{Content: "fixed"})
+	srv.HandleChat(func(req *ollama.ChatRequest) Reply {
+		return Reply{Content: "handled " + req.Messages[0].Content}
+	})
+	call := ollama.ToolCall{Function: ollama.Function{Name: "get_weather", Arguments: map[string]interface{}{"city": "Paris"}}}
+	srv.EnqueueReply(Reply{ToolCalls: []ollama.ToolCall{call}})
+
+	weather := ollama.Tool{Type: "function", Function: &ollama.ToolFunction{Name: "get_weather"}}
+	resp, err = client.Chat(ctx, &ollama.ChatRequest{Model: "llama3", Messages: []ollama.Message{{Role: "user", Content: "weather?"}}, Tools: []ollama.Tool{weather}})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.Message.ToolCalls) != 1 || resp.Message.ToolCalls[0].Function.Arguments["city"] != "Paris" {
+		t.Errorf("Expected tool call, got %+v", resp.Message)
+	}
+
+	resp, _ = client.Chat(ctx, &ollama.ChatRequest{Model: "llama3", Messages: []ollama.Message{{Role: "user", Content: "hi"}}})
+	if resp.Message.Content != "handled hi" {
+		t.Errorf("Expected handler reply, got %q", resp.Message.Content)
+	}
+
+	srv.HandleChat(nil)
+	resp, _ = client.Chat(ctx, &ollama.ChatRequest{Model: "llama3", Messages: []ollama.Message{{Role: "user", Content: "hi"}}})
+	if resp.Message.Content != "fixed" {
+		t.Errorf("Expected fixed reply, got %q", resp.Message.Content)
+	}
+
+	requests := srv.RequestsTo("/api/chat")
+	if len(requests) != 4 {
+		t.Fatalf("Expected 4 recorded chat requests, got %d", len(requests))
+	}
+	var recorded ollama.ChatRequest
+	if err := requests[1].Decode(&recorded); err != nil || len(recorded.Tools) != 1 {
+		t.Errorf("Expected recorded tools, got %+v (%v)", recorded, err)
+	}
+	if requests[1].Model() != "llama3" {
+		t.Errorf("Expected recorded model, got %q", requests[1].Model())
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddModel(Model{Name: "qwen3", Capabilities: []string{"completion", "thinking"}})
+	client := srv.Client()
+
+	srv.SetReply(Reply{Content: "abcdefg", ChunkSize: 3, Thinking: "hmm", ChunkDelay: 10 * time.Millisecond})
+
+	think := true
+	start := time.Now()
+	responseChan, errorChan := client.ChatStream(context.Background(), &ollama.ChatRequest{
+		Model:    "qwen3",
+		Messages: []ollama.Message{{Role: "user", Content: "go"}},
+		Think:    &think,
+	})
+
+	var chunks []string
+	var thinking string
+	var last *ollama.ChatResponse
+	for resp := range responseChan {
+		if resp.Message.Content != "" {
+			chunks = append(chunks, resp.Message.Content)
+		}
+		thinking += resp.Message.Thinking
+		last = resp
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(chunks, []string{"abc", "def", "g"}) {
+		t.Errorf("Unexpected chunks %q", chunks)
+	}
+	if thinking != "hmm" {
+		t.Errorf("Expected thinking, got %q", thinking)
+	}
+	if last == nil || !last.Done || last.EvalCount != 3 {
+		t.Errorf("Unexpected final frame: %+v", last)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected chunk delays, took %v", elapsed)
+	}
+
+	if got := splitContent("The sky  is blue", 0); !reflect.DeepEqual(got, []string{"The", " sky", "  is", " blue"}) {
+		t.Errorf("Unexpected word split %q", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddModel(Model{Name: "llama3"})
+	srv.AddModel(Model{Name: "nomic-embed-text", Capabilities: []string{"embedding"}})
+	client := srv.Client()
+	ctx := context.Background()
+
+	// Error frame mid-stream
+	srv.EnqueueReply(Reply{Content: "one two three", StreamError: "out of memory", ErrorAfter: 2})
+	responseChan, errorChan := client.GenerateStream(ctx, &ollama.GenerateRequest{Model: "llama3", Prompt: "count"})
+	var text string
+	for resp := range responseChan {
+		text += resp.Response
+	}
+	err := <-errorChan
+	if err == nil || !strings.Contains(err.Error(), "out of memory") {
+		t.Errorf("Expected stream error, got %v", err)
+	}
+	if text != "one two" {
+		t.Errorf("Expected two chunks before the error, got %q", text)
+	}
+
+	// Scripted HTTP error
+	srv.EnqueueReply(Reply{Error: "overloaded", Status: http.StatusServiceUnavailable})
+	_, err = client.Generate(ctx, &ollama.GenerateRequest{Model: "llama3", Prompt: "hi"})
+	var respErr *ollama.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusServiceUnavailable || respErr.Message != "overloaded" {
+		t.Errorf("Expected 503, got %v", err)
+	}
+
+	// Injected failure on any endpoint
+	srv.FailNext("/api/tags", http.StatusInternalServerError, "boom")
+	if _, err := client.List(ctx); err == nil {
+		t.Error("Expected List to fail once")
+	}
+	if _, err := client.List(ctx); err != nil {
+		t.Errorf("Expected List to recover, got %v", err)
+	}
+
+	// Unknown models and missing capabilities
+	if _, err := client.Chat(ctx, &ollama.ChatRequest{Model: "missing", Messages: []ollama.Message{{Role: "user", Content: "hi"}}}); !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown model, got %v", err)
+	}
+	_, err = client.Chat(ctx, &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "hi"}},
+		Tools:    []ollama.Tool{{Type: "function", Function: &ollama.ToolFunction{Name: "f"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not support tools") {
+		t.Errorf("Expected tools to be rejected, got %v", err)
+	}
+	if _, err := client.Generate(ctx, &ollama.GenerateRequest{Model: "nomic-embed-text", Prompt: "hi"}); err == nil {
+		t.Error("Expected generate on an embedding model to fail")
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddModel(Model{Name: "nomic-embed-text", Capabilities: []string{"embedding"}, EmbeddingLength: 8})
+	client := srv.Client()
+	ctx := context.Background()
+
+	resp, err := client.Embed(ctx, &ollama.EmbedRequest{Model: "nomic-embed-text", Input: []string{"a", "b", "a"}})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(resp.Embeddings) != 3 || len(resp.Embeddings[0]) != 8 {
+		t.Fatalf("Unexpected embeddings shape")
+	}
+	if !reflect.DeepEqual(resp.Embeddings[0], resp.Embeddings[2]) || reflect.DeepEqual(resp.Embeddings[0], resp.Embeddings[1]) {
+		t.Error("Expected deterministic, input-dependent embeddings")
+	}
+	if !reflect.DeepEqual(resp.Embeddings[1], FakeEmbedding("nomic-embed-text", "b", 8)) {
+		t.Error("Expected FakeEmbedding to match the server")
+	}
+
+	var norm float64
+	for _, v := range resp.Embeddings[0] {
+		norm += v * v
+	}
+	if math.Abs(norm-1) > 1e-9 {
+		t.Errorf("Expected unit vectors, got norm %v", norm)
+	}
+
+	resp, err = client.Embed(ctx, &ollama.EmbedRequest{Model: "nomic-embed-text", Input: "a", Dimensions: 4})
+	if err != nil || len(resp.Embeddings[0]) != 4 {
+		t.Errorf("Expected 4 dimensions, got %v %v", resp, err)
+	}
+
+	legacy, err := client.Embeddings(ctx, &ollama.EmbeddingsRequest{Model: "nomic-embed-text", Prompt: "a"})
+	if err != nil || !reflect.DeepEqual(legacy.Embedding, FakeEmbedding("nomic-embed-text", "a", 8)) {
+		t.Errorf("Unexpected legacy embedding: %v", err)
+	}
+}
+
+func TestModelManagement(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetVersion("0.9.0")
+	client := srv.Client()
+	ctx := context.Background()
+
+	if v, err := client.Version(ctx); err != nil || v.Version != "0.9.0" {
+		t.Errorf("Unexpected version %v %v", v, err)
+	}
+
+	var statuses []string
+	progressChan, errorChan := client.PullStream(ctx, &ollama.PullRequest{Model: "llama3"})
+	for p := range progressChan {
+		statuses = append(statuses, p.Status)
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("PullStream failed: %v", err)
+	}
+	if statuses[0] != "pulling manifest" || statuses[len(statuses)-1] != "success" {
+		t.Errorf("Unexpected pull statuses %v", statuses)
+	}
+
+	// Custom digests may be shorter than the prefix shown in statuses
+	srv.AddModel(Model{Name: "tiny", Digest: "abc"})
+	if _, err := client.Pull(ctx, &ollama.PullRequest{Model: "tiny"}); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if _, err := client.Push(ctx, &ollama.PushRequest{Model: "tiny"}); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if _, err := client.Delete(ctx, &ollama.DeleteRequest{Model: "tiny"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := client.Copy(ctx, &ollama.CopyRequest{Source: "llama3", Destination: "my-llama"}); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	digest, err := client.CreateBlobFrom(ctx, strings.NewReader("weights"), 7, nil)
+	if err != nil || !srv.HasBlob(digest) {
+		t.Fatalf("Blob upload failed: %v", err)
+	}
+	temperature := 0.5
+	_, err = client.Create(ctx, &ollama.CreateRequest{
+		Model:      "custom",
+		Files:      map[string]string{"model.gguf": digest},
+		System:     "Be brief.",
+		Parameters: &ollama.Options{Temperature: &temperature},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if got := srv.Models(); !reflect.DeepEqual(got, []string{"custom:latest", "llama3:latest", "my-llama:latest"}) {
+		t.Errorf("Unexpected models %v", got)
+	}
+
+	info, err := client.Show(ctx, &ollama.ShowRequest{Model: "custom"})
+	if err != nil || !strings.Contains(info.Parameters, "temperature") {
+		t.Errorf("Unexpected show response %+v %v", info, err)
+	}
+
+	if _, err := client.Delete(ctx, &ollama.DeleteRequest{Model: "my-llama"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := client.Delete(ctx, &ollama.DeleteRequest{Model: "my-llama"}); err == nil {
+		t.Error("Expected deleting a missing model to fail")
+	}
+
+	list, err := client.List(ctx)
+	if err != nil || len(list.Models) != 2 {
+		t.Errorf("Expected 2 models, got %v %v", list, err)
+	}
+}
+
+func TestResidency(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddModel(Model{Name: "llama3"})
+	client := srv.Client()
+	ctx := context.Background()
+
+	if err := client.Load(ctx, "llama3", ollama.KeepForever().Duration); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	ps, err := client.Ps(ctx)
+	if err != nil || len(ps.Models) != 1 || ps.Models[0].ExpiresAt.Before(time.Now().AddDate(1, 0, 0)) {
+		t.Fatalf("Expected llama3 to be loaded forever, got %+v %v", ps, err)
+	}
+
+	if err := client.Unload(ctx, "llama3"); err != nil {
+		t.Fatalf("Unload failed: %v", err)
+	}
+	if ps, _ := client.Ps(ctx); len(ps.Models) != 0 {
+		t.Errorf("Expected no running models, got %+v", ps.Models)
+	}
+
+	srv.LoadModel("llama3", time.Now().Add(-time.Second))
+	if ps, _ := client.Ps(ctx); len(ps.Models) != 0 {
+		t.Error("Expected expired models to be dropped")
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/liliang-cn/ollama-go/otel
+
+go 1.21
+
+require (
+	github.com/liliang-cn/ollama-go v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+)
+
+// Until a release of the root module includes the middleware API, build
+// against the local checkout.
+replace github.com/liliang-cn/ollama-go => ../
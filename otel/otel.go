@@ -0,0 +1,326 @@
+/*
+Package otel traces ollama client calls with OpenTelemetry.
+
+Every request gets a client span named after the GenAI semantic conventions,
+e.g. "chat llama3", carrying the model, the request parameters, the token
+counts and finish reason from the final response frame and, for streaming
+calls, the time to first token. The trace context is injected into the request
+headers.
+
+	client, err := ollama.NewClient(
+		ollama.WithMiddleware(otel.Middleware()),
+	)
+
+Prompts and completions are not recorded unless WithContentRecording is set.
+
+This package lives in its own module so that the ollama package itself has no
+dependencies.
+*/
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/liliang-cn/ollama-go/otel"
+
+// Attribute keys from the GenAI semantic conventions, plus a few
+// Ollama-specific ones.
+const (
+	AttrOperationName       = attribute.Key("gen_ai.operation.name")
+	AttrSystem              = attribute.Key("gen_ai.system")
+	AttrRequestModel        = attribute.Key("gen_ai.request.model")
+	AttrRequestTemperature  = attribute.Key("gen_ai.request.temperature")
+	AttrRequestTopP         = attribute.Key("gen_ai.request.top_p")
+	AttrRequestTopK         = attribute.Key("gen_ai.request.top_k")
+	AttrRequestMaxTokens    = attribute.Key("gen_ai.request.max_tokens")
+	AttrRequestSeed         = attribute.Key("gen_ai.request.seed")
+	AttrRequestStop         = attribute.Key("gen_ai.request.stop_sequences")
+	AttrResponseModel       = attribute.Key("gen_ai.response.model")
+	AttrResponseFinish      = attribute.Key("gen_ai.response.finish_reasons")
+	AttrUsageInputTokens    = attribute.Key("gen_ai.usage.input_tokens")
+	AttrUsageOutputTokens   = attribute.Key("gen_ai.usage.output_tokens")
+	AttrTimeToFirstToken    = attribute.Key("gen_ai.response.time_to_first_token")
+	AttrErrorType           = attribute.Key("error.type")
+	AttrHTTPMethod          = attribute.Key("http.request.method")
+	AttrHTTPStatusCode      = attribute.Key("http.response.status_code")
+	AttrOllamaEndpoint      = attribute.Key("ollama.endpoint")
+	AttrOllamaStream        = attribute.Key("ollama.stream")
+	AttrOllamaLoadDuration  = attribute.Key("ollama.load_duration")
+	AttrOllamaTotalDuration = attribute.Key("ollama.total_duration")
+)
+
+// Operation names used for gen_ai.operation.name.
+const (
+	OperationChat           = "chat"
+	OperationTextCompletion = "text_completion"
+	OperationEmbeddings     = "embeddings"
+)
+
+// Span event names.
+const (
+	EventFirstToken = "gen_ai.first_token"
+	EventPrompt     = "gen_ai.content.prompt"
+	EventCompletion = "gen_ai.content.completion"
+)
+
+type config struct {
+	provider      trace.TracerProvider
+	propagator    propagation.TextMapPropagator
+	recordContent bool
+}
+
+// Option configures Middleware.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider. The global provider is used by
+// default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to inject the trace context into
+// request headers. The global propagator is used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// WithContentRecording records prompts and completions as span events. They
+// may contain sensitive data, so this is off by default. Images are never
+// recorded.
+func WithContentRecording(enabled bool) Option {
+	return func(c *config) {
+		c.recordContent = enabled
+	}
+}
+
+// Middleware returns an ollama.Middleware that traces every request.
+func Middleware(opts ...Option) ollama.Middleware {
+	cfg := config{
+		provider:   otelapi.GetTracerProvider(),
+		propagator: otelapi.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.provider.Tracer(ScopeName)
+
+	return func(next ollama.RoundTripFunc) ollama.RoundTripFunc {
+		return func(ctx context.Context, req *ollama.Request) (*http.Response, error) {
+			operation := operationName(req.Endpoint)
+			model := req.Model()
+
+			name := operation
+			if model != "" {
+				name += " " + model
+			}
+
+			attrs := []attribute.KeyValue{
+				AttrSystem.String("ollama"),
+				AttrHTTPMethod.String(req.Method),
+				AttrOllamaEndpoint.String(req.Endpoint),
+			}
+			if isGenAI(operation) {
+				attrs = append(attrs, AttrOperationName.String(operation))
+			}
+			if model != "" {
+				attrs = append(attrs, AttrRequestModel.String(model))
+			}
+			attrs = append(attrs, requestAttributes(req.Body)...)
+
+			start := time.Now()
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithTimestamp(start),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			if cfg.recordContent {
+				recordPrompt(span, req.Body)
+			}
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				recordError(span, err)
+				span.End()
+				return nil, err
+			}
+
+			span.SetAttributes(AttrHTTPStatusCode.Int(resp.StatusCode))
+			ollama.ObserveResponse(resp, cfg.recordContent, func(stats ollama.ResponseStats) {
+				recordResponse(span, start, stats, cfg.recordContent)
+				span.End(trace.WithTimestamp(stats.End))
+			})
+			return resp, nil
+		}
+	}
+}
+
+// operationName maps an endpoint to a GenAI operation, or to the endpoint's
+// name for the management API, e.g. "ollama pull".
+func operationName(endpoint string) string {
+	switch endpoint {
+	case "/api/chat":
+		return OperationChat
+	case "/api/generate":
+		return OperationTextCompletion
+	case "/api/embed", "/api/embeddings":
+		return OperationEmbeddings
+	}
+
+	// Blob endpoints carry a digest, keep only the first path segment
+	name := strings.TrimPrefix(endpoint, "/api/")
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[:i]
+	}
+	return "ollama " + name
+}
+
+func isGenAI(operation string) bool {
+	return operation == OperationChat || operation == OperationTextCompletion || operation == OperationEmbeddings
+}
+
+func requestAttributes(body interface{}) []attribute.KeyValue {
+	var options *ollama.Options
+	var stream *bool
+	switch b := body.(type) {
+	case *ollama.ChatRequest:
+		options, stream = b.Options, b.Stream
+	case *ollama.GenerateRequest:
+		options, stream = b.Options, b.Stream
+	case *ollama.EmbedRequest:
+		options = b.Options
+	case *ollama.EmbeddingsRequest:
+		options = b.Options
+	}
+
+	var attrs []attribute.KeyValue
+	if stream != nil {
+		attrs = append(attrs, AttrOllamaStream.Bool(*stream))
+	}
+	if options == nil {
+		return attrs
+	}
+	if options.Temperature != nil {
+		attrs = append(attrs, AttrRequestTemperature.Float64(*options.Temperature))
+	}
+	if options.TopP != nil {
+		attrs = append(attrs, AttrRequestTopP.Float64(*options.TopP))
+	}
+	if options.TopK != nil {
+		attrs = append(attrs, AttrRequestTopK.Int(*options.TopK))
+	}
+	if options.NumPredict != nil {
+		attrs = append(attrs, AttrRequestMaxTokens.Int(*options.NumPredict))
+	}
+	if options.Seed != nil {
+		attrs = append(attrs, AttrRequestSeed.Int(*options.Seed))
+	}
+	if len(options.Stop) > 0 {
+		attrs = append(attrs, AttrRequestStop.StringSlice(options.Stop))
+	}
+	return attrs
+}
+
+// recordPrompt adds the prompt as a span event, leaving out images.
+func recordPrompt(span trace.Span, body interface{}) {
+	var prompt string
+	switch b := body.(type) {
+	case *ollama.ChatRequest:
+		type message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}
+		messages := make([]message, len(b.Messages))
+		for i, m := range b.Messages {
+			messages[i] = message{Role: m.Role, Content: m.Content}
+		}
+		data, _ := json.Marshal(messages)
+		prompt = string(data)
+	case *ollama.GenerateRequest:
+		prompt = b.Prompt
+	case *ollama.EmbedRequest:
+		data, _ := json.Marshal(b.Input)
+		prompt = string(data)
+	case *ollama.EmbeddingsRequest:
+		prompt = b.Prompt
+	default:
+		return
+	}
+
+	span.AddEvent(EventPrompt, trace.WithAttributes(attribute.String("gen_ai.prompt", prompt)))
+}
+
+func recordResponse(span trace.Span, start time.Time, stats ollama.ResponseStats, recordContent bool) {
+	usage := stats.Usage
+
+	var attrs []attribute.KeyValue
+	if usage.Model != "" {
+		attrs = append(attrs, AttrResponseModel.String(usage.Model))
+	}
+	if usage.DoneReason != "" {
+		attrs = append(attrs, AttrResponseFinish.StringSlice([]string{usage.DoneReason}))
+	}
+	if usage.PromptEvalCount > 0 {
+		attrs = append(attrs, AttrUsageInputTokens.Int(usage.PromptEvalCount))
+	}
+	if usage.EvalCount > 0 {
+		attrs = append(attrs, AttrUsageOutputTokens.Int(usage.EvalCount))
+	}
+	if usage.LoadDuration > 0 {
+		attrs = append(attrs, AttrOllamaLoadDuration.Float64(usage.LoadDuration.Seconds()))
+	}
+	if usage.TotalDuration > 0 {
+		attrs = append(attrs, AttrOllamaTotalDuration.Float64(usage.TotalDuration.Seconds()))
+	}
+	span.SetAttributes(attrs...)
+
+	if stats.Streaming && !stats.FirstToken.IsZero() {
+		span.AddEvent(EventFirstToken, trace.WithTimestamp(stats.FirstToken))
+		span.SetAttributes(AttrTimeToFirstToken.Float64(stats.FirstToken.Sub(start).Seconds()))
+	}
+
+	if recordContent && stats.Content != "" {
+		span.AddEvent(EventCompletion, trace.WithAttributes(attribute.String("gen_ai.completion", stats.Content)))
+	}
+
+	if stats.Err != nil {
+		recordError(span, stats.Err)
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	errorType := fmt.Sprintf("%T", err)
+	var respErr *ollama.ResponseError
+	if errors.As(err, &respErr) {
+		errorType = strconv.Itoa(respErr.StatusCode)
+		if respErr.StatusCode != http.StatusOK {
+			span.SetAttributes(AttrHTTPStatusCode.Int(respErr.StatusCode))
+		}
+	} else if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		errorType = err.Error()
+	}
+
+	span.SetAttributes(AttrErrorType.String(errorType))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,198 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ollama "github.com/liliang-cn/ollama-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*ollama.Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	opts = append([]Option{WithTracerProvider(provider), WithPropagator(propagation.TraceContext{})}, opts...)
+
+	client, err := ollama.NewClient(ollama.WithHost(server.URL), ollama.WithMiddleware(Middleware(opts...)))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client, exporter
+}
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestChatSpan(t *testing.T) {
+	var traceparent string
+	client, exporter := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		fmt.Fprint(w, `{"model":"llama3:latest","message":{"role":"assistant","content":"Hello"},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":5,"load_duration":2000000}`)
+	})
+
+	temperature := 0.2
+	_, err := client.Chat(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "Hi"}},
+		Options:  &ollama.Options{Temperature: &temperature},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "chat llama3" {
+		t.Errorf("Expected span name 'chat llama3', got %q", span.Name)
+	}
+	if traceparent == "" || traceparent[3:35] != span.SpanContext.TraceID().String() {
+		t.Errorf("Expected trace context in headers, got %q", traceparent)
+	}
+
+	a := attrs(span)
+	checks := map[attribute.Key]interface{}{
+		AttrOperationName:      "chat",
+		AttrSystem:             "ollama",
+		AttrRequestModel:       "llama3",
+		AttrResponseModel:      "llama3:latest",
+		AttrUsageInputTokens:   int64(12),
+		AttrUsageOutputTokens:  int64(5),
+		AttrRequestTemperature: 0.2,
+		AttrOllamaLoadDuration: 0.002,
+	}
+	for key, expected := range checks {
+		if got := a[key].AsInterface(); got != expected {
+			t.Errorf("Attribute %s: expected %v, got %v", key, expected, got)
+		}
+	}
+	if reasons := a[AttrResponseFinish].AsStringSlice(); len(reasons) != 1 || reasons[0] != "stop" {
+		t.Errorf("Expected finish reason stop, got %v", reasons)
+	}
+	if _, ok := a[AttrTimeToFirstToken]; ok {
+		t.Error("Expected no time to first token for a non-streaming call")
+	}
+	if len(span.Events) != 0 {
+		t.Errorf("Expected no content events by default, got %v", span.Events)
+	}
+}
+
+func TestStreamingSpan(t *testing.T) {
+	client, exporter := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher := w.(http.Flusher)
+		fmt.Fprintln(w, `{"model":"llama3","response":"","done":false}`)
+		flusher.Flush()
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, `{"model":"llama3","response":"Hel","done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","response":"lo","done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","response":"","done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}`)
+	}, WithContentRecording(true))
+
+	responseChan, errorChan := client.GenerateStream(context.Background(), &ollama.GenerateRequest{Model: "llama3", Prompt: "Say hello"})
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	a := attrs(span)
+
+	if a[AttrOperationName].AsString() != OperationTextCompletion {
+		t.Errorf("Expected text_completion, got %v", a[AttrOperationName].AsString())
+	}
+	if a[AttrUsageOutputTokens].AsInt64() != 2 {
+		t.Errorf("Expected 2 output tokens, got %v", a[AttrUsageOutputTokens].AsInt64())
+	}
+	if ttft := a[AttrTimeToFirstToken].AsFloat64(); ttft < 0.02 {
+		t.Errorf("Expected time to first token of at least 20ms, got %v", ttft)
+	}
+
+	events := make(map[string]string)
+	for _, event := range span.Events {
+		events[event.Name] = ""
+		for _, kv := range event.Attributes {
+			events[event.Name] = kv.Value.AsString()
+		}
+	}
+	if _, ok := events[EventFirstToken]; !ok {
+		t.Error("Expected first token event")
+	}
+	if events[EventPrompt] != "Say hello" {
+		t.Errorf("Expected prompt event, got %q", events[EventPrompt])
+	}
+	if events[EventCompletion] != "Hello" {
+		t.Errorf("Expected completion event, got %q", events[EventCompletion])
+	}
+}
+
+func TestErrorSpan(t *testing.T) {
+	client, exporter := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model not found"}`)
+	})
+
+	if _, err := client.Pull(context.Background(), &ollama.PullRequest{Model: "missing"}); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "ollama pull missing" {
+		t.Errorf("Unexpected span name %q", span.Name)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected error status, got %v", span.Status)
+	}
+	a := attrs(span)
+	if a[AttrErrorType].AsString() != "404" || a[AttrHTTPStatusCode].AsInt64() != 404 {
+		t.Errorf("Unexpected error attributes: %v %v", a[AttrErrorType], a[AttrHTTPStatusCode])
+	}
+	if _, ok := a[AttrOperationName]; ok {
+		t.Error("Expected no GenAI operation for pull")
+	}
+}
+
+func TestOperationName(t *testing.T) {
+	tests := map[string]string{
+		"/api/chat":              "chat",
+		"/api/generate":          "text_completion",
+		"/api/embed":             "embeddings",
+		"/api/embeddings":        "embeddings",
+		"/api/tags":              "ollama tags",
+		"/api/blobs/sha256:abcd": "ollama blobs",
+	}
+	for endpoint, expected := range tests {
+		if got := operationName(endpoint); got != expected {
+			t.Errorf("operationName(%q) = %q, expected %q", endpoint, got, expected)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package ollama
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter is a single named model parameter, as found in
+// ShowResponse.Parameters or a Modelfile PARAMETER line.
+type Parameter struct {
+	Name  string
+	Value string
+}
+
+// Set assigns a parameter given in its text form to the Options field with
+// the matching JSON name (e.g. "temperature", "num_ctx"). Setting "stop"
+// appends to the existing stop sequences. Parameters without a field are
+// kept in Extra, with numbers and booleans converted; setting one again
+// collects the values into a list.
+func (o *Options) Set(name, value string) error {
+	v := reflect.ValueOf(o).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if optionName(t.Field(i)) != name || t.Field(i).Type.Kind() == reflect.Map {
+			continue
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Slice {
+			field.Set(reflect.Append(field, reflect.ValueOf(value)))
+			return nil
+		}
+
+		elem := reflect.New(field.Type().Elem())
+		switch field.Type().Elem().Kind() {
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid integer for %s: %q", name, value)
+			}
+			elem.Elem().SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid number for %s: %q", name, value)
+			}
+			elem.Elem().SetFloat(f)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean for %s: %q", name, value)
+			}
+			elem.Elem().SetBool(b)
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	o.setExtra(name, extraValue(value))
+	return nil
+}
+
+func (o *Options) setExtra(name string, value interface{}) {
+	if o.Extra == nil {
+		o.Extra = make(map[string]interface{})
+	}
+	switch existing := o.Extra[name].(type) {
+	case nil:
+		o.Extra[name] = value
+	case []interface{}:
+		o.Extra[name] = append(existing, value)
+	default:
+		o.Extra[name] = []interface{}{existing, value}
+	}
+}
+
+// extraValue converts the text form of an unknown parameter to the JSON
+// value the server expects.
+func extraValue(value string) interface{} {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	return value
+}
+
+// MarshalJSON encodes the options with the Extra parameters merged in.
+func (o Options) MarshalJSON() ([]byte, error) {
+	type plain Options
+	data, err := json.Marshal(plain(o))
+	if err != nil || len(o.Extra) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range o.Extra {
+		if _, ok := merged[name]; ok {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for parameter %s: %w", name, err)
+		}
+		merged[name] = raw
+	}
+	return json.Marshal(merged)
+}
+
+// Parameters lists the options that are set, in field order followed by the
+// Extra parameters sorted by name, with one entry per stop sequence or list
+// element.
+func (o *Options) Parameters() []Parameter {
+	var params []Parameter
+
+	v := reflect.ValueOf(o).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		name := optionName(t.Field(i))
+
+		switch {
+		case field.Kind() == reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				params = append(params, Parameter{Name: name, Value: field.Index(j).String()})
+			}
+		case field.Kind() == reflect.Ptr && !field.IsNil():
+			params = append(params, Parameter{Name: name, Value: fmt.Sprint(field.Elem().Interface())})
+		}
+	}
+
+	names := make([]string, 0, len(o.Extra))
+	for name := range o.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values, ok := o.Extra[name].([]interface{})
+		if !ok {
+			values = []interface{}{o.Extra[name]}
+		}
+		for _, value := range values {
+			params = append(params, Parameter{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+
+	return params
+}
+
+// ParseParameters parses the newline-delimited parameter text returned in
+// ShowResponse.Parameters, e.g.
+//
+//	stop                           "<|eot_id|>"
+//	temperature                    0.7
+//
+// Repeated stop entries are collected into Options.Stop. Parameters the
+// client has no field for are kept in Options.Extra.
+func ParseParameters(s string) (*Options, error) {
+	opts := &Options{}
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		name, value := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name, value = line[:i], strings.TrimSpace(line[i:])
+		}
+		if value == "" {
+			return nil, fmt.Errorf("parameters line %d: missing value for %s", lineNo, name)
+		}
+
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("parameters line %d: invalid quoted value %s", lineNo, value)
+			}
+			value = unquoted
+		}
+
+		if err := opts.Set(name, value); err != nil {
+			return nil, fmt.Errorf("parameters line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read parameters: %w", err)
+	}
+
+	return opts, nil
+}
+
+// FormatParameters renders options in the same text form the server uses for
+// ShowResponse.Parameters. String values are quoted.
+func FormatParameters(opts *Options) string {
+	if opts == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, p := range opts.Parameters() {
+		value := p.Value
+		if _, ok := extraValue(value).(string); ok || p.Name == "stop" {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, "%-30s %s\n", p.Name, value)
+	}
+	return b.String()
+}
+
+// Options parses the model's default parameters into Options.
+func (r *ShowResponse) Options() (*Options, error) {
+	return ParseParameters(r.Parameters)
+}
+
+func optionName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
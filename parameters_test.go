@@ -0,0 +1,120 @@
+package ollama
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const showParameters = `num_ctx                        8192
+stop                           "<|start_header_id|>"
+stop                           "<|end_header_id|>"
+stop                           "say \"bye\""
+temperature                    0.7
+use_mmap                       true
+`
+
+func TestParseParameters(t *testing.T) {
+	opts, err := ParseParameters(showParameters)
+	if err != nil {
+		t.Fatalf("ParseParameters failed: %v", err)
+	}
+
+	if opts.NumCtx == nil || *opts.NumCtx != 8192 {
+		t.Errorf("Expected num_ctx 8192, got %v", opts.NumCtx)
+	}
+	if opts.Temperature == nil || *opts.Temperature != 0.7 {
+		t.Errorf("Expected temperature 0.7, got %v", opts.Temperature)
+	}
+	if opts.UseMmap == nil || !*opts.UseMmap {
+		t.Errorf("Expected use_mmap true, got %v", opts.UseMmap)
+	}
+
+	expectedStop := []string{"<|start_header_id|>", "<|end_header_id|>", `say "bye"`}
+	if !reflect.DeepEqual(opts.Stop, expectedStop) {
+		t.Errorf("Expected stop %v, got %v", expectedStop, opts.Stop)
+	}
+}
+
+func TestParseParametersErrors(t *testing.T) {
+	tests := []string{
+		"temperature",
+		"temperature hot",
+		`stop "unterminated`,
+	}
+
+	for _, input := range tests {
+		if _, err := ParseParameters(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestParseParametersUnknown(t *testing.T) {
+	opts, err := ParseParameters("temperature 0.5\nnum_experts 8\nflash true\nmarker \"x\"\nmarker \"y\"\n")
+	if err != nil {
+		t.Fatalf("Expected unknown parameters to be kept, got %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"num_experts": int64(8),
+		"flash":       true,
+		"marker":      []interface{}{"x", "y"},
+	}
+	if !reflect.DeepEqual(opts.Extra, expected) {
+		t.Errorf("Expected extra %v, got %v", expected, opts.Extra)
+	}
+
+	again, err := ParseParameters(FormatParameters(opts))
+	if err != nil || !reflect.DeepEqual(opts, again) {
+		t.Errorf("Options differ after round trip: %+v vs %+v (%v)", opts, again, err)
+	}
+
+	data, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"flash":true,"marker":["x","y"],"num_experts":8,"temperature":0.5}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	if data, _ := json.Marshal(&Options{TopK: IntPtr(3)}); string(data) != `{"top_k":3}` {
+		t.Errorf("Unexpected JSON without extra parameters: %s", data)
+	}
+}
+
+func TestFormatParametersRoundTrip(t *testing.T) {
+	opts, err := ParseParameters(showParameters)
+	if err != nil {
+		t.Fatalf("ParseParameters failed: %v", err)
+	}
+
+	// Override a default and render the result for a derived model
+	opts.Temperature = Float64Ptr(0.2)
+
+	again, err := ParseParameters(FormatParameters(opts))
+	if err != nil {
+		t.Fatalf("ParseParameters of rendered text failed: %v", err)
+	}
+	if !reflect.DeepEqual(opts, again) {
+		t.Errorf("Options differ after round trip: %+v vs %+v", opts, again)
+	}
+
+	if FormatParameters(nil) != "" {
+		t.Error("Expected empty string for nil options")
+	}
+}
+
+func TestShowResponseOptions(t *testing.T) {
+	show := &ShowResponse{Parameters: "top_k 40\nstop \"</s>\""}
+
+	opts, err := show.Options()
+	if err != nil {
+		t.Fatalf("Options failed: %v", err)
+	}
+	if opts.TopK == nil || *opts.TopK != 40 {
+		t.Errorf("Expected top_k 40, got %v", opts.TopK)
+	}
+	if len(opts.Stop) != 1 || opts.Stop[0] != "</s>" {
+		t.Errorf("Expected stop [</s>], got %v", opts.Stop)
+	}
+}
@@ -0,0 +1,608 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolMaxFailures  = 3
+	defaultPoolEjectionTime = 30 * time.Second
+	defaultPoolPsInterval   = 10 * time.Second
+)
+
+// API is the method set of Client. A Pool has the same methods, so code
+// written against API works with one server or several.
+type API interface {
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+	GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan *GenerateResponse, <-chan error)
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+	ChatStream(ctx context.Context, req *ChatRequest) (<-chan *ChatResponse, <-chan error)
+	Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error)
+	Embeddings(ctx context.Context, req *EmbeddingsRequest) (*EmbeddingsResponse, error)
+	ValidateEmbedDimensions(ctx context.Context, model string, dimensions int) error
+
+	List(ctx context.Context) (*ListResponse, error)
+	Show(ctx context.Context, req *ShowRequest) (*ShowResponse, error)
+	Ps(ctx context.Context) (*ProcessResponse, error)
+	WatchPs(ctx context.Context, interval time.Duration) <-chan PsEvent
+	Version(ctx context.Context) (*VersionResponse, error)
+	ModelCapabilities(ctx context.Context, model string) ([]Capability, error)
+	ClearCapabilityCache()
+
+	Pull(ctx context.Context, req *PullRequest) (*StatusResponse, error)
+	PullStream(ctx context.Context, req *PullRequest) (<-chan *ProgressResponse, <-chan error)
+	PullWithRetry(ctx context.Context, req *PullRequest, opts *RetryOptions) (<-chan *ProgressResponse, <-chan error)
+	EnsureModel(ctx context.Context, name string, opts *EnsureOptions) (*ModelInfo, error)
+	Push(ctx context.Context, req *PushRequest) (*StatusResponse, error)
+	PushStream(ctx context.Context, req *PushRequest) (<-chan *ProgressResponse, <-chan error)
+	Create(ctx context.Context, req *CreateRequest) (*StatusResponse, error)
+	CreateStream(ctx context.Context, req *CreateRequest) (<-chan *ProgressResponse, <-chan error)
+	CreateFromDirectory(ctx context.Context, name, dir string, opts *CreateDirectoryOptions) (*StatusResponse, error)
+	Delete(ctx context.Context, req *DeleteRequest) (*StatusResponse, error)
+	Copy(ctx context.Context, req *CopyRequest) (*StatusResponse, error)
+
+	CreateBlob(ctx context.Context, path string) (string, error)
+	CreateBlobFile(ctx context.Context, path string, opts *BlobOptions) (string, error)
+	CreateBlobFrom(ctx context.Context, r io.Reader, size int64, opts *BlobOptions) (string, error)
+	CreateBlobs(ctx context.Context, paths []string, opts *BlobOptions) (map[string]string, error)
+	CheckBlob(ctx context.Context, digest string) (bool, error)
+
+	Load(ctx context.Context, model string, keepAlive time.Duration) error
+	Unload(ctx context.Context, model string) error
+	PinModels(ctx context.Context, models []string, opts *PinOptions) <-chan []PinStatus
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*Pool)(nil)
+)
+
+// PoolStrategy selects the host that serves a request.
+type PoolStrategy int
+
+const (
+	// RoundRobin sends requests to each healthy host in turn (the default).
+	RoundRobin PoolStrategy = iota
+	// LeastInFlight sends requests to the healthy host with the fewest
+	// requests in flight, counting open streams.
+	LeastInFlight
+	// ModelAffinity prefers healthy hosts that have the request's model
+	// loaded, as seen in their /api/ps and in recent responses, and falls
+	// back to LeastInFlight.
+	ModelAffinity
+)
+
+// PoolOptions configures a Pool. Zero values use the defaults.
+type PoolOptions struct {
+	Strategy PoolStrategy
+
+	// MaxAttempts limits the hosts tried by one request when failing over
+	// (default: every host). 1 disables failover.
+	MaxAttempts int
+
+	// MaxFailures is the number of consecutive failures after which a host
+	// is ejected (default 3). Connection errors and 5xx statuses count as
+	// failures.
+	MaxFailures int
+	// EjectionTime is how long an ejected host receives no requests
+	// (default 30s). After that it gets requests again, and one more failure
+	// ejects it again.
+	EjectionTime time.Duration
+
+	// PsInterval is how often ModelAffinity refreshes the running models of
+	// each host (default 10s). Refreshes happen in the background, on
+	// demand.
+	PsInterval time.Duration
+}
+
+// Pool is a client that spreads requests over several Ollama servers.
+//
+// Every Client method is available on a Pool, and the embedded Client can be
+// passed to code that expects a *Client (its calls made of several requests
+// then spread them over the hosts). Each request is routed to one host
+// chosen by the strategy; List, Ps and Version therefore describe a single
+// host, and model management calls such as Pull or Delete only affect the
+// host they are routed to.
+//
+// Calls made of several requests (CreateFromDirectory, the blob uploads,
+// EnsureModel, PullWithRetry, Load, Unload, PinModels and WatchPs) send all
+// of them to one host; use WithAffinity to do the same across calls, e.g. to
+// upload blobs and then create a model from them.
+//
+// Hosts are health-checked passively: after MaxFailures consecutive
+// failures a host is ejected for EjectionTime. When every host is ejected,
+// the one whose ejection ends first is used.
+//
+// Requests that fail with a connection error, a 5xx or a 429 status are
+// retried on another host. Non-streaming generate, chat, embed, show, list,
+// ps and version calls are failed over even when the connection drops while
+// the response is read. Streaming calls are only failed over before the
+// response starts, and requests that change a host (pull, push, create,
+// copy, delete, blob uploads) are never failed over.
+type Pool struct {
+	*Client
+
+	opts  PoolOptions
+	hosts []*poolHost
+
+	mu   sync.Mutex
+	next int
+}
+
+type poolHost struct {
+	url *url.URL
+
+	inFlight     int
+	failures     int
+	ejectedUntil time.Time
+
+	models     map[string]bool
+	psAt       time.Time
+	refreshing bool
+}
+
+// HostStatus describes the state of a pool host.
+type HostStatus struct {
+	URL      string
+	InFlight int
+	// Failures is the number of consecutive failures.
+	Failures     int
+	Ejected      bool
+	EjectedUntil time.Time
+	// Models are the models known to be loaded, sorted.
+	Models []string
+}
+
+// NewPool creates a pool over the given hosts. Client options such as
+// WithHTTPClient, WithHeaders or WithMiddleware apply to every host;
+// middleware runs once per call, before the host is chosen.
+//
+// Example:
+//
+//	pool, err := ollama.NewPool(
+//		[]string{"http://gpu1:11434", "http://gpu2:11434"},
+//		&ollama.PoolOptions{Strategy: ollama.ModelAffinity},
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	resp, err := pool.Chat(ctx, req)
+func NewPool(hosts []string, opts *PoolOptions, options ...ClientOption) (*Pool, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("ollama: pool needs at least one host")
+	}
+
+	var o PoolOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxAttempts <= 0 || o.MaxAttempts > len(hosts) {
+		o.MaxAttempts = len(hosts)
+	}
+	if o.MaxFailures <= 0 {
+		o.MaxFailures = defaultPoolMaxFailures
+	}
+	if o.EjectionTime <= 0 {
+		o.EjectionTime = defaultPoolEjectionTime
+	}
+	if o.PsInterval <= 0 {
+		o.PsInterval = defaultPoolPsInterval
+	}
+
+	p := &Pool{opts: o}
+	for _, host := range hosts {
+		u, err := url.Parse(host)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid host URL %q", host)
+		}
+		p.hosts = append(p.hosts, &poolHost{url: u, models: make(map[string]bool)})
+	}
+
+	client, err := NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+	client.transport = p.send
+	p.Client = client
+
+	return p, nil
+}
+
+// Status returns the state of every host, in the order they were given.
+func (p *Pool) Status() []HostStatus {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]HostStatus, len(p.hosts))
+	for i, h := range p.hosts {
+		statuses[i] = HostStatus{
+			URL:          h.url.String(),
+			InFlight:     h.inFlight,
+			Failures:     h.failures,
+			Ejected:      now.Before(h.ejectedUntil),
+			EjectedUntil: h.ejectedUntil,
+			Models:       sortedKeys(h.models),
+		}
+	}
+	return statuses
+}
+
+// poolAffinityKey is the context key of a poolAffinity.
+type poolAffinityKey struct{}
+
+// poolAffinity ties the requests made with a context to one host of a pool.
+type poolAffinity struct {
+	pool *Pool
+	host *poolHost
+}
+
+// WithAffinity returns a context whose requests through this pool all go to
+// the same host, chosen now for model (which may be empty). They are not
+// failed over to other hosts. If ctx already has an affinity to a host of
+// the pool it is returned unchanged.
+//
+// Example:
+//
+//	ctx = pool.WithAffinity(ctx, "my-model")
+//	digest, err := pool.CreateBlobFile(ctx, "model.gguf", nil)
+//	...
+//	_, err = pool.Create(ctx, &ollama.CreateRequest{
+//		Model: "my-model",
+//		Files: map[string]string{"model.gguf": digest},
+//	})
+func (p *Pool) WithAffinity(ctx context.Context, model string) context.Context {
+	if p.affinity(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, poolAffinityKey{}, poolAffinity{pool: p, host: p.pick(model, nil)})
+}
+
+// affinity returns the host ctx is tied to in this pool, or nil.
+func (p *Pool) affinity(ctx context.Context) *poolHost {
+	if a, ok := ctx.Value(poolAffinityKey{}).(poolAffinity); ok && a.pool == p {
+		return a.host
+	}
+	return nil
+}
+
+// affinityHost returns the URL of the pool host ctx is tied to, or "".
+func affinityHost(ctx context.Context) string {
+	if a, ok := ctx.Value(poolAffinityKey{}).(poolAffinity); ok {
+		return a.host.url.String()
+	}
+	return ""
+}
+
+// CreateFromDirectory is Client.CreateFromDirectory with the blob uploads
+// and the create sent to one host.
+func (p *Pool) CreateFromDirectory(ctx context.Context, name, dir string, opts *CreateDirectoryOptions) (*StatusResponse, error) {
+	return p.Client.CreateFromDirectory(p.WithAffinity(ctx, name), name, dir, opts)
+}
+
+// CreateBlob is Client.CreateBlob with the check and the upload sent to one
+// host.
+func (p *Pool) CreateBlob(ctx context.Context, path string) (string, error) {
+	return p.Client.CreateBlob(p.WithAffinity(ctx, ""), path)
+}
+
+// CreateBlobFile is Client.CreateBlobFile with the check and the upload sent
+// to one host.
+func (p *Pool) CreateBlobFile(ctx context.Context, path string, opts *BlobOptions) (string, error) {
+	return p.Client.CreateBlobFile(p.WithAffinity(ctx, ""), path, opts)
+}
+
+// CreateBlobFrom is Client.CreateBlobFrom with the check and the upload sent
+// to one host.
+func (p *Pool) CreateBlobFrom(ctx context.Context, r io.Reader, size int64, opts *BlobOptions) (string, error) {
+	return p.Client.CreateBlobFrom(p.WithAffinity(ctx, ""), r, size, opts)
+}
+
+// CreateBlobs is Client.CreateBlobs with every blob uploaded to one host.
+func (p *Pool) CreateBlobs(ctx context.Context, paths []string, opts *BlobOptions) (map[string]string, error) {
+	return p.Client.CreateBlobs(p.WithAffinity(ctx, ""), paths, opts)
+}
+
+// EnsureModel is Client.EnsureModel with the checks and the pull sent to one
+// host. It makes sure the model is available on that host only.
+func (p *Pool) EnsureModel(ctx context.Context, name string, opts *EnsureOptions) (*ModelInfo, error) {
+	return p.Client.EnsureModel(p.WithAffinity(ctx, name), name, opts)
+}
+
+// PullWithRetry is Client.PullWithRetry with every attempt sent to one host,
+// so that retries resume the same download.
+func (p *Pool) PullWithRetry(ctx context.Context, req *PullRequest, opts *RetryOptions) (<-chan *ProgressResponse, <-chan error) {
+	return p.Client.PullWithRetry(p.WithAffinity(ctx, req.Model), req, opts)
+}
+
+// Load is Client.Load with the capability check and the load sent to one
+// host.
+func (p *Pool) Load(ctx context.Context, model string, keepAlive time.Duration) error {
+	return p.Client.Load(p.WithAffinity(ctx, model), model, keepAlive)
+}
+
+// Unload is Client.Unload with the capability check and the unload sent to
+// one host.
+func (p *Pool) Unload(ctx context.Context, model string) error {
+	return p.Client.Unload(p.WithAffinity(ctx, model), model)
+}
+
+// PinModels is Client.PinModels on a single host of the pool.
+func (p *Pool) PinModels(ctx context.Context, models []string, opts *PinOptions) <-chan []PinStatus {
+	return p.Client.PinModels(p.WithAffinity(ctx, ""), models, opts)
+}
+
+// WatchPs is Client.WatchPs on a single host of the pool, so successive
+// snapshots can be compared.
+func (p *Pool) WatchPs(ctx context.Context, interval time.Duration) <-chan PsEvent {
+	return p.Client.WatchPs(p.WithAffinity(ctx, ""), interval)
+}
+
+// send is the pool's RoundTripFunc: it picks a host, sends the request and
+// fails over to other hosts when allowed.
+func (p *Pool) send(ctx context.Context, r *Request) (*http.Response, error) {
+	if host := p.affinity(ctx); host != nil {
+		return p.sendTo(ctx, host, r, false)
+	}
+
+	attempts := 1
+	if failoverEndpoints[r.Endpoint] && r.RawBody == nil {
+		attempts = p.opts.MaxAttempts
+	}
+	buffer := attempts > 1 && !isStreamingRequest(r)
+
+	tried := make(map[*poolHost]bool)
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		host := p.pick(r.Model(), tried)
+		if host == nil {
+			break
+		}
+		tried[host] = true
+
+		resp, err := p.sendTo(ctx, host, r, buffer)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !isRetryable(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// failoverEndpoints are the endpoints that can be re-sent to another host.
+var failoverEndpoints = map[string]bool{
+	"/api/generate":   true,
+	"/api/chat":       true,
+	"/api/embed":      true,
+	"/api/embeddings": true,
+	"/api/show":       true,
+	"/api/tags":       true,
+	"/api/ps":         true,
+	"/api/version":    true,
+}
+
+// isStreamingRequest reports whether the response is a stream of frames.
+func isStreamingRequest(r *Request) bool {
+	switch b := r.Body.(type) {
+	case *GenerateRequest:
+		return b.Stream == nil || *b.Stream
+	case *ChatRequest:
+		return b.Stream == nil || *b.Stream
+	}
+	return false
+}
+
+// sendTo sends a request to one host and tracks its load and health. When
+// buffer is set the response body is read before returning, so a dropped
+// connection is reported as an error that can be failed over.
+func (p *Pool) sendTo(ctx context.Context, host *poolHost, r *Request, buffer bool) (*http.Response, error) {
+	p.mu.Lock()
+	host.inFlight++
+	p.mu.Unlock()
+
+	resp, err := p.Client.sendTo(ctx, host.url, r)
+	if err != nil {
+		p.done(host, r, err)
+		return nil, err
+	}
+
+	if buffer {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			err = fmt.Errorf("failed to read response: %w", err)
+			p.done(host, r, err)
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		p.done(host, r, nil)
+		return resp, nil
+	}
+
+	ObserveResponse(resp, false, func(stats ResponseStats) {
+		p.done(host, r, stats.Err)
+	})
+	return resp, nil
+}
+
+// done records the outcome of a request to host.
+func (p *Pool) done(host *poolHost, r *Request, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	host.inFlight--
+	switch {
+	case err == nil:
+		host.failures = 0
+		host.ejectedUntil = time.Time{}
+		if model := modelKey(r.Model()); model != "" && loadsModel(r.Endpoint) {
+			host.models[model] = true
+		}
+	case isHostFailure(err):
+		host.failures++
+		if host.failures >= p.opts.MaxFailures {
+			host.ejectedUntil = time.Now().Add(p.opts.EjectionTime)
+		}
+	}
+}
+
+// isHostFailure reports whether err says something about the host's health
+// rather than the request.
+func isHostFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500
+	}
+	return true
+}
+
+func loadsModel(endpoint string) bool {
+	switch endpoint {
+	case "/api/generate", "/api/chat", "/api/embed", "/api/embeddings":
+		return true
+	}
+	return false
+}
+
+// pick chooses a host that has not been tried yet, or returns nil when all
+// have been.
+func (p *Pool) pick(model string, tried map[*poolHost]bool) *poolHost {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy []*poolHost
+	var fallback *poolHost
+	for _, h := range p.hosts {
+		if tried[h] {
+			continue
+		}
+		if !now.Before(h.ejectedUntil) {
+			healthy = append(healthy, h)
+		} else if fallback == nil || h.ejectedUntil.Before(fallback.ejectedUntil) {
+			fallback = h
+		}
+	}
+	if len(healthy) == 0 {
+		return fallback
+	}
+
+	switch p.opts.Strategy {
+	case LeastInFlight:
+		return p.leastInFlight(healthy)
+	case ModelAffinity:
+		p.refreshModels(now)
+		if key := modelKey(model); key != "" {
+			var warm []*poolHost
+			for _, h := range healthy {
+				if h.models[key] {
+					warm = append(warm, h)
+				}
+			}
+			if len(warm) > 0 {
+				return p.leastInFlight(warm)
+			}
+		}
+		return p.leastInFlight(healthy)
+	}
+
+	h := healthy[p.next%len(healthy)]
+	p.next++
+	return h
+}
+
+// leastInFlight returns the host with the fewest requests in flight. Ties
+// are broken in turn so idle hosts share the load. p.mu must be held.
+func (p *Pool) leastInFlight(hosts []*poolHost) *poolHost {
+	start := p.next % len(hosts)
+	p.next++
+
+	best := hosts[start]
+	for i := 1; i < len(hosts); i++ {
+		h := hosts[(start+i)%len(hosts)]
+		if h.inFlight < best.inFlight {
+			best = h
+		}
+	}
+	return best
+}
+
+// refreshModels starts a background /api/ps refresh for every host whose
+// view is older than PsInterval. p.mu must be held.
+func (p *Pool) refreshModels(now time.Time) {
+	for _, h := range p.hosts {
+		if h.refreshing || now.Sub(h.psAt) < p.opts.PsInterval {
+			continue
+		}
+		h.refreshing = true
+		go p.refresh(h)
+	}
+}
+
+func (p *Pool) refresh(host *poolHost) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.PsInterval)
+	defer cancel()
+
+	models, err := p.runningModels(ctx, host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	host.refreshing = false
+	host.psAt = time.Now()
+	if err == nil {
+		host.models = models
+	}
+}
+
+// runningModels asks host which models it has loaded. The request is pinned
+// to host but otherwise made like any other, so middleware that adds
+// credentials applies to it as well.
+func (p *Pool) runningModels(ctx context.Context, host *poolHost) (map[string]bool, error) {
+	ctx = context.WithValue(ctx, poolAffinityKey{}, poolAffinity{pool: p, host: host})
+	ps, err := p.Client.Ps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make(map[string]bool, len(ps.Models))
+	for _, m := range ps.Models {
+		name := m.Model
+		if name == "" {
+			name = m.Name
+		}
+		if key := modelKey(name); key != "" {
+			models[key] = true
+		}
+	}
+	return models, nil
+}
+
+// modelKey normalises a model name so "llama3" and "llama3:latest" match.
+func modelKey(model string) string {
+	if model == "" {
+		return ""
+	}
+	ref, err := ParseModelRef(model)
+	if err != nil {
+		return model
+	}
+	ref.Digest = ""
+	return ref.ShortString()
+}
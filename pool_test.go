@@ -0,0 +1,326 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func chatRequest(model string) *ChatRequest {
+	return &ChatRequest{Model: model, Messages: []Message{{Role: "user", Content: "hi"}}}
+}
+
+func TestNewPoolErrors(t *testing.T) {
+	if _, err := NewPool(nil, nil); err == nil {
+		t.Error("Expected an error for an empty pool")
+	}
+	if _, err := NewPool([]string{"localhost"}, nil); err == nil {
+		t.Error("Expected an error for a host without scheme")
+	}
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	hits := make([]int32, 3)
+	var urls []string
+	for i := range hits {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+			_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+
+	pool, err := NewPool(urls, nil)
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, err := pool.Chat(context.Background(), chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+	for i := range hits {
+		if n := atomic.LoadInt32(&hits[i]); n != 2 {
+			t.Errorf("Expected host %d to serve 2 requests, got %d", i, n)
+		}
+	}
+}
+
+func TestPoolFailoverAndEjection(t *testing.T) {
+	var badHits, goodHits int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&badHits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":"overloaded"}`))
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&goodHits, 1)
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+	}))
+	defer good.Close()
+
+	var calls int32
+	counter := func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return next(ctx, req)
+		}
+	}
+
+	pool, err := NewPool([]string{bad.URL, good.URL}, &PoolOptions{MaxFailures: 2, EjectionTime: time.Hour}, WithMiddleware(counter))
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		resp, err := pool.Chat(context.Background(), chatRequest("llama3"))
+		if err != nil {
+			t.Fatalf("Chat %d failed: %v", i, err)
+		}
+		if resp.Message.Content != "ok" {
+			t.Errorf("Unexpected response %+v", resp)
+		}
+	}
+
+	if n := atomic.LoadInt32(&badHits); n != 2 {
+		t.Errorf("Expected the failing host to be ejected after 2 failures, got %d requests", n)
+	}
+	if n := atomic.LoadInt32(&calls); n != 6 {
+		t.Errorf("Expected middleware to run once per call, got %d", n)
+	}
+
+	status := pool.Status()
+	if !status[0].Ejected || status[0].Failures != 2 || status[1].Ejected {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// Requests that change a host are not failed over
+	pool, _ = NewPool([]string{bad.URL, good.URL}, nil)
+	before := atomic.LoadInt32(&goodHits)
+	if _, err := pool.Delete(context.Background(), &DeleteRequest{Model: "llama3"}); err == nil {
+		t.Error("Expected Delete to fail on the first host")
+	}
+	if atomic.LoadInt32(&goodHits) != before {
+		t.Error("Expected Delete not to be failed over")
+	}
+}
+
+func TestPoolFailoverOnDroppedBody(t *testing.T) {
+	var droppedHits, goodHits int32
+	dropped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&droppedHits, 1)
+		w.Header().Set("Content-Length", "1000")
+		_, _ = w.Write([]byte(`{"model":`))
+	}))
+	defer dropped.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&goodHits, 1)
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+	}))
+	defer good.Close()
+
+	pool, _ := NewPool([]string{dropped.URL, good.URL}, nil)
+	if _, err := pool.Chat(context.Background(), chatRequest("llama3")); err != nil {
+		t.Fatalf("Expected failover after a dropped body, got %v", err)
+	}
+	if a, b := atomic.LoadInt32(&droppedHits), atomic.LoadInt32(&goodHits); a != 1 || b != 1 {
+		t.Errorf("Expected one request per host, got %d and %d", a, b)
+	}
+
+	// Everything is ejected: the pool still uses the host that comes back first
+	single, _ := NewPool([]string{dropped.URL}, &PoolOptions{MaxFailures: 1})
+	_, _ = single.Chat(context.Background(), chatRequest("llama3"))
+	if !single.Status()[0].Ejected {
+		t.Fatal("Expected the host to be ejected")
+	}
+	_, _ = single.Chat(context.Background(), chatRequest("llama3"))
+	if n := atomic.LoadInt32(&droppedHits); n != 3 {
+		t.Errorf("Expected the ejected host to be used as a last resort, got %d requests", n)
+	}
+}
+
+func TestPoolLeastInFlight(t *testing.T) {
+	release := make(chan struct{})
+	hits := make([]int32, 2)
+	var urls []string
+	for i := range hits {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+			switch r.URL.Path {
+			case "/api/chat":
+				_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Content: "a"}})
+				w.(http.Flusher).Flush()
+				<-release
+				_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Done: true})
+			case "/api/version":
+				_ = json.NewEncoder(w).Encode(VersionResponse{Version: "0.9.0"})
+			}
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	defer close(release)
+
+	pool, _ := NewPool(urls, &PoolOptions{Strategy: LeastInFlight})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	responseChan, _ := pool.ChatStream(ctx, chatRequest("llama3"))
+	<-responseChan
+
+	busy := 0
+	if atomic.LoadInt32(&hits[1]) == 1 {
+		busy = 1
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Version(context.Background()); err != nil {
+			t.Fatalf("Version failed: %v", err)
+		}
+	}
+
+	status := pool.Status()
+	if status[busy].InFlight != 1 {
+		t.Errorf("Expected the open stream to count as in flight, got %+v", status)
+	}
+	if n := atomic.LoadInt32(&hits[1-busy]); n != 3 {
+		t.Errorf("Expected the idle host to serve every request, got %d", n)
+	}
+}
+
+func TestPoolModelAffinity(t *testing.T) {
+	var coldChats int32
+	cold := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ps":
+			_ = json.NewEncoder(w).Encode(ProcessResponse{})
+		case "/api/chat":
+			atomic.AddInt32(&coldChats, 1)
+			_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+		case "/api/version":
+			_ = json.NewEncoder(w).Encode(VersionResponse{Version: "0.9.0"})
+		}
+	}))
+	defer cold.Close()
+	warm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/ps":
+			_ = json.NewEncoder(w).Encode(ProcessResponse{Models: []ProcessModel{{Name: "llama3:latest", Model: "llama3:latest"}}})
+		case "/api/chat":
+			_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+		case "/api/version":
+			_ = json.NewEncoder(w).Encode(VersionResponse{Version: "0.9.0"})
+		}
+	}))
+	defer warm.Close()
+
+	// The /api/ps refresh carries the credentials added by middleware too
+	auth := HeaderMiddleware(map[string]string{"Authorization": "Bearer token"})
+	pool, _ := NewPool([]string{cold.URL, warm.URL}, &PoolOptions{Strategy: ModelAffinity}, WithMiddleware(auth))
+	ctx := context.Background()
+
+	// The first request starts the /api/ps refresh
+	if _, err := pool.Version(ctx); err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !reflect.DeepEqual(pool.Status()[1].Models, []string{"llama3:latest"}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the running models, got %+v", pool.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := pool.Chat(ctx, chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&coldChats); n != 0 {
+		t.Errorf("Expected llama3 requests to go to the warm host, cold host served %d", n)
+	}
+
+	// A successful request marks its model as loaded on the host
+	if _, err := pool.Chat(ctx, chatRequest("qwen3")); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	var loaded []string
+	for _, status := range pool.Status() {
+		loaded = append(loaded, status.Models...)
+	}
+	if len(loaded) != 2 {
+		t.Errorf("Expected qwen3 to be recorded as loaded, got %v", loaded)
+	}
+}
+
+func TestPoolHostAffinity(t *testing.T) {
+	hits := make([]int32, 2)
+	var urls []string
+	for i := range hits {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+			switch {
+			case r.Method == http.MethodHead:
+				w.WriteHeader(http.StatusNotFound)
+			case r.URL.Path == "/api/chat":
+				_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "ok"}, Done: true})
+			default:
+				w.WriteHeader(http.StatusCreated)
+			}
+		}))
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	count := func(i int) int { return int(atomic.LoadInt32(&hits[i])) }
+
+	pool, _ := NewPool(urls, nil)
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	// Every check and upload of one call goes to the same host
+	if _, err := pool.CreateBlobs(context.Background(), paths, nil); err != nil {
+		t.Fatalf("CreateBlobs failed: %v", err)
+	}
+	if a, b := count(0), count(1); a*b != 0 || a+b != 6 {
+		t.Errorf("Expected all 6 requests on one host, got %d and %d", a, b)
+	}
+
+	// And so does every request made with an affinity context
+	ctx := pool.WithAffinity(context.Background(), "llama3")
+	if pool.WithAffinity(ctx, "llama3") != ctx {
+		t.Error("Expected an existing affinity to be kept")
+	}
+	before := []int{count(0), count(1)}
+	for i := 0; i < 4; i++ {
+		if _, err := pool.Chat(ctx, chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+	if a, b := count(0)-before[0], count(1)-before[1]; a*b != 0 || a+b != 4 {
+		t.Errorf("Expected all 4 chats on one host, got %d and %d", a, b)
+	}
+}
@@ -0,0 +1,250 @@
+package ollama
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressPhase is the stage of a pull, push or create operation, derived
+// from ProgressResponse.Status.
+type ProgressPhase string
+
+// Phases reported by the server during pull, push and create.
+const (
+	PhaseUnknown         ProgressPhase = ""
+	PhaseManifest        ProgressPhase = "manifest"
+	PhaseDownloading     ProgressPhase = "downloading"
+	PhaseUploading       ProgressPhase = "uploading"
+	PhaseProcessing      ProgressPhase = "processing"
+	PhaseVerifying       ProgressPhase = "verifying"
+	PhaseWritingManifest ProgressPhase = "writing manifest"
+	PhaseCleanup         ProgressPhase = "cleanup"
+	PhaseSuccess         ProgressPhase = "success"
+)
+
+const (
+	// rateSampleInterval is the minimum time between transfer rate samples.
+	rateSampleInterval = 200 * time.Millisecond
+	// rateSmoothing is the weight of the newest sample in the moving average.
+	rateSmoothing = 0.3
+)
+
+// LayerProgress is the transfer state of a single layer digest.
+type LayerProgress struct {
+	Digest    string
+	Total     int64
+	Completed int64
+}
+
+// ProgressSnapshot is the aggregated state of an operation across all layers.
+type ProgressSnapshot struct {
+	Phase     ProgressPhase
+	Status    string
+	Completed int64
+	Total     int64
+	// Percent is Completed/Total in the range 0-100, or 0 while no total is known.
+	Percent float64
+	// Rate is the smoothed transfer rate in bytes per second.
+	Rate float64
+	// ETA is the estimated time remaining, or 0 if it cannot be estimated.
+	ETA     time.Duration
+	Elapsed time.Duration
+	Layers  []LayerProgress
+}
+
+// ProgressEvent is returned by ProgressTracker.Update for every frame.
+type ProgressEvent struct {
+	ProgressSnapshot
+	// PhaseChanged is true when this frame moved the operation to a new phase.
+	PhaseChanged  bool
+	PreviousPhase ProgressPhase
+}
+
+// ProgressTracker aggregates the per-layer ProgressResponse frames emitted by
+// PullStream, PushStream and CreateStream into overall bytes, percentage,
+// transfer rate and ETA. It is safe for concurrent use.
+//
+// Example:
+//
+//	tracker := ollama.NewProgressTracker()
+//	for p := range progressChan {
+//		ev := tracker.Update(p)
+//		if ev.PhaseChanged {
+//			fmt.Println(ev.Status)
+//		}
+//		fmt.Printf("\r%.1f%% %.0f B/s ETA %s", ev.Percent, ev.Rate, ev.ETA)
+//	}
+type ProgressTracker struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	start  time.Time
+	phase  ProgressPhase
+	status string
+	layers map[string]*LayerProgress
+	order  []string
+
+	rate       float64
+	sampleAt   time.Time
+	sampleDone int64
+}
+
+// NewProgressTracker creates an empty tracker.
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{
+		now:    time.Now,
+		layers: make(map[string]*LayerProgress),
+	}
+}
+
+// Update records a progress frame and returns the aggregated state.
+// Completed bytes for a layer never go backwards, so repeated or reordered
+// frames do not make the overall percentage jump.
+func (t *ProgressTracker) Update(p *ProgressResponse) ProgressEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.start.IsZero() {
+		t.start = now
+		t.sampleAt = now
+	}
+
+	if p.Digest != "" {
+		layer, ok := t.layers[p.Digest]
+		if !ok {
+			layer = &LayerProgress{Digest: p.Digest}
+			t.layers[p.Digest] = layer
+			t.order = append(t.order, p.Digest)
+		}
+		if p.Total > 0 {
+			layer.Total = p.Total
+		}
+		if p.Completed > layer.Completed {
+			layer.Completed = p.Completed
+		}
+	}
+
+	previous := t.phase
+	if phase := classifyStatus(p.Status); phase != PhaseUnknown {
+		t.phase = phase
+	}
+	if p.Status != "" {
+		t.status = p.Status
+	}
+
+	t.sampleRate(now)
+
+	return ProgressEvent{
+		ProgressSnapshot: t.snapshot(now),
+		PhaseChanged:     t.phase != previous,
+		PreviousPhase:    previous,
+	}
+}
+
+// Snapshot returns the current aggregated state.
+func (t *ProgressTracker) Snapshot() ProgressSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot(t.now())
+}
+
+// Track consumes a progress channel until it is closed, calling fn with every
+// event, and returns the final snapshot.
+func (t *ProgressTracker) Track(progressChan <-chan *ProgressResponse, fn func(ProgressEvent)) ProgressSnapshot {
+	for p := range progressChan {
+		ev := t.Update(p)
+		if fn != nil {
+			fn(ev)
+		}
+	}
+	return t.Snapshot()
+}
+
+// sampleRate folds the bytes transferred since the last sample into the
+// exponentially smoothed rate.
+func (t *ProgressTracker) sampleRate(now time.Time) {
+	elapsed := now.Sub(t.sampleAt)
+	if elapsed < rateSampleInterval {
+		return
+	}
+
+	completed, _ := t.totals()
+	delta := completed - t.sampleDone
+	if delta < 0 {
+		delta = 0
+	}
+
+	sample := float64(delta) / elapsed.Seconds()
+	if t.rate == 0 {
+		t.rate = sample
+	} else {
+		t.rate = rateSmoothing*sample + (1-rateSmoothing)*t.rate
+	}
+
+	t.sampleAt = now
+	t.sampleDone = completed
+}
+
+func (t *ProgressTracker) totals() (completed, total int64) {
+	for _, layer := range t.layers {
+		completed += layer.Completed
+		total += layer.Total
+	}
+	return completed, total
+}
+
+func (t *ProgressTracker) snapshot(now time.Time) ProgressSnapshot {
+	completed, total := t.totals()
+
+	s := ProgressSnapshot{
+		Phase:     t.phase,
+		Status:    t.status,
+		Completed: completed,
+		Total:     total,
+		Rate:      t.rate,
+		Layers:    make([]LayerProgress, 0, len(t.order)),
+	}
+	if !t.start.IsZero() {
+		s.Elapsed = now.Sub(t.start)
+	}
+	if total > 0 {
+		s.Percent = float64(completed) / float64(total) * 100
+	}
+	if t.rate > 0 && total > completed {
+		s.ETA = time.Duration(float64(total-completed) / t.rate * float64(time.Second))
+	}
+	for _, digest := range t.order {
+		s.Layers = append(s.Layers, *t.layers[digest])
+	}
+
+	return s
+}
+
+// classifyStatus maps a server status message to a phase.
+func classifyStatus(status string) ProgressPhase {
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	switch {
+	case status == "":
+		return PhaseUnknown
+	case status == "success":
+		return PhaseSuccess
+	case strings.HasSuffix(status, "manifest") && strings.HasPrefix(status, "writing"):
+		return PhaseWritingManifest
+	case strings.HasSuffix(status, "manifest"):
+		// "pulling manifest", "retrieving manifest", "pushing manifest"
+		return PhaseManifest
+	case strings.HasPrefix(status, "verifying"):
+		return PhaseVerifying
+	case strings.HasPrefix(status, "removing"):
+		return PhaseCleanup
+	case strings.HasPrefix(status, "pulling") || strings.HasPrefix(status, "downloading"):
+		return PhaseDownloading
+	case strings.HasPrefix(status, "pushing") || strings.HasPrefix(status, "uploading"):
+		return PhaseUploading
+	default:
+		// parsing modelfile, creating/using layers, copying, converting, quantizing
+		return PhaseProcessing
+	}
+}
@@ -0,0 +1,112 @@
+package ollama
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestTracker() (*ProgressTracker, *time.Time) {
+	now := time.Unix(0, 0)
+	tracker := NewProgressTracker()
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestProgressTrackerAggregatesLayers(t *testing.T) {
+	tracker, now := newTestTracker()
+
+	ev := tracker.Update(&ProgressResponse{Status: "pulling manifest"})
+	if !ev.PhaseChanged || ev.Phase != PhaseManifest {
+		t.Errorf("Expected phase change to manifest, got %+v", ev)
+	}
+
+	tracker.Update(&ProgressResponse{Status: "pulling aaa", Digest: "sha256:aaa", Total: 1000})
+	tracker.Update(&ProgressResponse{Status: "pulling bbb", Digest: "sha256:bbb", Total: 3000})
+
+	*now = now.Add(time.Second)
+	ev = tracker.Update(&ProgressResponse{Status: "pulling aaa", Digest: "sha256:aaa", Total: 1000, Completed: 1000})
+	if ev.PhaseChanged {
+		t.Error("Switching layers should not change phase")
+	}
+	if ev.Phase != PhaseDownloading {
+		t.Errorf("Expected downloading phase, got %q", ev.Phase)
+	}
+	if ev.Total != 4000 || ev.Completed != 1000 || ev.Percent != 25 {
+		t.Errorf("Unexpected totals: %+v", ev.ProgressSnapshot)
+	}
+	if ev.Rate != 1000 {
+		t.Errorf("Expected rate 1000 B/s, got %f", ev.Rate)
+	}
+	if ev.ETA != 3*time.Second {
+		t.Errorf("Expected ETA 3s, got %s", ev.ETA)
+	}
+
+	// A stale frame for a finished layer must not move progress backwards
+	ev = tracker.Update(&ProgressResponse{Status: "pulling aaa", Digest: "sha256:aaa", Total: 1000, Completed: 500})
+	if ev.Completed != 1000 {
+		t.Errorf("Expected completed to stay at 1000, got %d", ev.Completed)
+	}
+
+	*now = now.Add(time.Second)
+	ev = tracker.Update(&ProgressResponse{Status: "pulling bbb", Digest: "sha256:bbb", Total: 3000, Completed: 3000})
+	if ev.Percent != 100 || ev.ETA != 0 {
+		t.Errorf("Expected completion, got %+v", ev.ProgressSnapshot)
+	}
+	// Smoothed: 0.3*3000 + 0.7*1000
+	if ev.Rate != 1600 {
+		t.Errorf("Expected smoothed rate 1600, got %f", ev.Rate)
+	}
+
+	phases := []struct {
+		status string
+		phase  ProgressPhase
+	}{
+		{"verifying sha256 digest", PhaseVerifying},
+		{"writing manifest", PhaseWritingManifest},
+		{"removing any unused layers", PhaseCleanup},
+		{"success", PhaseSuccess},
+	}
+	for _, p := range phases {
+		ev = tracker.Update(&ProgressResponse{Status: p.status})
+		if !ev.PhaseChanged || ev.Phase != p.phase {
+			t.Errorf("Status %q: expected change to %q, got %+v", p.status, p.phase, ev)
+		}
+	}
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot.Layers) != 2 || snapshot.Layers[0].Digest != "sha256:aaa" {
+		t.Errorf("Unexpected layers: %+v", snapshot.Layers)
+	}
+	if snapshot.Elapsed != 2*time.Second {
+		t.Errorf("Expected elapsed 2s, got %s", snapshot.Elapsed)
+	}
+}
+
+func TestProgressTrackerTrack(t *testing.T) {
+	progressChan := make(chan *ProgressResponse, 4)
+	progressChan <- &ProgressResponse{Status: "parsing modelfile"}
+	progressChan <- &ProgressResponse{Status: "pushing abc", Digest: "sha256:abc", Total: 10, Completed: 5}
+	progressChan <- &ProgressResponse{Status: "pushing manifest"}
+	progressChan <- &ProgressResponse{Status: "success"}
+	close(progressChan)
+
+	var changes []ProgressPhase
+	final := NewProgressTracker().Track(progressChan, func(ev ProgressEvent) {
+		if ev.PhaseChanged {
+			changes = append(changes, ev.Phase)
+		}
+	})
+
+	expected := []ProgressPhase{PhaseProcessing, PhaseUploading, PhaseManifest, PhaseSuccess}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected phases %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Phase %d: expected %q, got %q", i, expected[i], changes[i])
+		}
+	}
+	if final.Completed != 5 || final.Phase != PhaseSuccess {
+		t.Errorf("Unexpected final snapshot: %+v", final)
+	}
+}
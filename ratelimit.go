@@ -0,0 +1,247 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateSweepInterval is how often a rate limiter drops the buckets of idle
+// keys.
+const rateSweepInterval = time.Minute
+
+// ErrRateLimited is returned by non-blocking rate limiters when a request is
+// over budget. The error is a *RateLimitError.
+var ErrRateLimited = errors.New("ollama: rate limited")
+
+// RateLimitError reports which budget a request exceeded and when it can be
+// retried.
+type RateLimitError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: key %q, retry after %v", ErrRateLimited, e.Key, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrRateLimited) true.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimitOptions configures WithRateLimit. Budgets left at zero are not
+// limited.
+type RateLimitOptions struct {
+	// RequestsPerSecond is the sustained request rate per key.
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once (default:
+	// RequestsPerSecond rounded up, at least 1).
+	Burst int
+
+	// TokensPerMinute is the token budget per key, counting prompt and
+	// generated tokens. Token counts are only known when a response is
+	// complete, so they are charged afterwards: a key that has overspent
+	// waits until its budget is positive again.
+	TokensPerMinute int
+
+	// KeyFunc returns the key whose budget a request uses (default:
+	// TenantFromContext, so requests without a tenant share one budget).
+	KeyFunc func(ctx context.Context) string
+
+	// NonBlocking fails requests over budget with a *RateLimitError instead
+	// of waiting for the budget to refill.
+	NonBlocking bool
+}
+
+// WithRateLimit limits the rate of chat, generate and embed requests with a
+// token bucket per key. Other requests are not limited.
+//
+// The option can be given more than once to combine budgets, e.g. one per
+// tenant and one for the whole client with a KeyFunc that returns a constant.
+// A request then has to pass every limiter in turn; when a later one rejects
+// it, the request still counts against the earlier ones.
+//
+// Example:
+//
+//	client, err := ollama.NewClient(
+//		ollama.WithRateLimit(&ollama.RateLimitOptions{
+//			RequestsPerSecond: 5,
+//			TokensPerMinute:   100000,
+//			NonBlocking:       true,
+//		}),
+//	)
+//	...
+//	resp, err := client.Chat(ollama.ContextWithTenant(ctx, "search-team"), req)
+//	if errors.Is(err, ollama.ErrRateLimited) {
+//		...
+//	}
+//
+// A client-wide budget on top of the per-tenant ones:
+//
+//	client, err := ollama.NewClient(
+//		ollama.WithRateLimit(&ollama.RateLimitOptions{RequestsPerSecond: 5}),
+//		ollama.WithRateLimit(&ollama.RateLimitOptions{
+//			RequestsPerSecond: 20,
+//			KeyFunc:           func(context.Context) string { return "client" },
+//		}),
+//	)
+func WithRateLimit(opts *RateLimitOptions) ClientOption {
+	var o RateLimitOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Burst <= 0 {
+		o.Burst = int(math.Max(1, math.Ceil(o.RequestsPerSecond)))
+	}
+	if o.KeyFunc == nil {
+		o.KeyFunc = TenantFromContext
+	}
+
+	limiter := &rateLimiter{opts: o, buckets: make(map[string]*rateBucket)}
+	return WithMiddleware(limiter.middleware)
+}
+
+type rateLimiter struct {
+	opts RateLimitOptions
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+	swept   time.Time
+}
+
+// rateBucket holds the remaining budget of one key. Both budgets refill
+// continuously up to their capacity; tokens can go negative.
+type rateBucket struct {
+	requests float64
+	tokens   float64
+	updated  time.Time
+}
+
+func (l *rateLimiter) middleware(next RoundTripFunc) RoundTripFunc {
+	return func(ctx context.Context, req *Request) (*http.Response, error) {
+		if !loadsModel(req.Endpoint) {
+			return next(ctx, req)
+		}
+
+		key := l.opts.KeyFunc(ctx)
+		if err := l.wait(ctx, key); err != nil {
+			return nil, err
+		}
+
+		resp, err := next(ctx, req)
+		if err != nil || l.opts.TokensPerMinute <= 0 {
+			return resp, err
+		}
+		ObserveResponse(resp, false, func(stats ResponseStats) {
+			l.charge(key, stats.Usage.PromptEvalCount+stats.Usage.EvalCount)
+		})
+		return resp, nil
+	}
+}
+
+// wait takes a request from key's budget, waiting for it to refill unless
+// the limiter is non-blocking.
+func (l *rateLimiter) wait(ctx context.Context, key string) error {
+	for {
+		delay := l.take(key)
+		if delay == 0 {
+			return nil
+		}
+		if l.opts.NonBlocking {
+			return &RateLimitError{Key: key, RetryAfter: delay}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take takes a request from key's budget if possible, or returns how long
+// to wait before trying again.
+func (l *rateLimiter) take(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, time.Now())
+
+	var delay time.Duration
+	if l.opts.RequestsPerSecond > 0 && b.requests < 1 {
+		delay = durationFor(1-b.requests, l.opts.RequestsPerSecond)
+	}
+	if l.opts.TokensPerMinute > 0 && b.tokens < 0 {
+		if d := durationFor(-b.tokens, float64(l.opts.TokensPerMinute)/60); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	b.requests--
+	return 0
+}
+
+// charge takes the tokens used by a response from key's budget.
+func (l *rateLimiter) charge(key string, tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.bucket(key, time.Now()).tokens -= float64(tokens)
+}
+
+// bucket returns key's bucket refilled up to now. l.mu must be held.
+func (l *rateLimiter) bucket(key string, now time.Time) *rateBucket {
+	if now.Sub(l.swept) >= rateSweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{
+			requests: float64(l.opts.Burst),
+			tokens:   float64(l.opts.TokensPerMinute),
+			updated:  now,
+		}
+		l.buckets[key] = b
+		return b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	b.updated = now
+	b.requests = math.Min(float64(l.opts.Burst), b.requests+elapsed*l.opts.RequestsPerSecond)
+	b.tokens = math.Min(float64(l.opts.TokensPerMinute), b.tokens+elapsed*float64(l.opts.TokensPerMinute)/60)
+	return b
+}
+
+// sweep drops the buckets that have refilled completely: they are the same
+// as the new bucket a key gets, so only idle keys are forgotten. l.mu must be
+// held.
+func (l *rateLimiter) sweep(now time.Time) {
+	l.swept = now
+	for key, b := range l.buckets {
+		elapsed := now.Sub(b.updated).Seconds()
+		requestsFull := l.opts.RequestsPerSecond <= 0 ||
+			b.requests+elapsed*l.opts.RequestsPerSecond >= float64(l.opts.Burst)
+		tokensFull := l.opts.TokensPerMinute <= 0 ||
+			b.tokens+elapsed*float64(l.opts.TokensPerMinute)/60 >= float64(l.opts.TokensPerMinute)
+		if requestsFull && tokensFull {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// durationFor returns the time needed to refill amount at rate per second,
+// rounded up so that the budget is available when it has passed.
+func durationFor(amount, rate float64) time.Duration {
+	return time.Duration(math.Ceil(amount / rate * float64(time.Second)))
+}
@@ -0,0 +1,172 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRequests(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"ok"},"done":true,"prompt_eval_count":1,"eval_count":1}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithRateLimit(&RateLimitOptions{
+		RequestsPerSecond: 20,
+		Burst:             2,
+		NonBlocking:       true,
+	}))
+	ctx := ContextWithTenant(context.Background(), "search")
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat(ctx, chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat %d failed: %v", i, err)
+		}
+	}
+
+	_, err := client.Chat(ctx, chatRequest("llama3"))
+	var limitErr *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limitErr) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+	if limitErr.Key != "search" || limitErr.RetryAfter <= 0 || limitErr.RetryAfter > 50*time.Millisecond {
+		t.Errorf("Unexpected error %+v", limitErr)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected the limited request not to be sent, got %d requests", n)
+	}
+
+	// Keys have separate budgets, and other endpoints are not limited
+	if _, err := client.Chat(ContextWithTenant(context.Background(), "reports"), chatRequest("llama3")); err != nil {
+		t.Errorf("Expected another key to have its own budget, got %v", err)
+	}
+	if _, err := client.Version(ctx); err != nil {
+		t.Errorf("Expected Version not to be limited, got %v", err)
+	}
+
+	time.Sleep(limitErr.RetryAfter)
+	if _, err := client.Chat(ctx, chatRequest("llama3")); err != nil {
+		t.Errorf("Expected the budget to refill, got %v", err)
+	}
+}
+
+func TestRateLimitBlocking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"ok"},"done":true,"prompt_eval_count":1,"eval_count":1}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithRateLimit(&RateLimitOptions{RequestsPerSecond: 20, Burst: 1}))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Chat(context.Background(), chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat %d failed: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected requests to be spaced by 50ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.Chat(ctx, chatRequest("llama3")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestRateLimitTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"ok"},"done":true,"prompt_eval_count":400,"eval_count":210}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL), WithRateLimit(&RateLimitOptions{
+		TokensPerMinute: 6000,
+		NonBlocking:     true,
+	}))
+	ctx := context.Background()
+
+	// 100 tokens per second: ten responses of 610 tokens overspend by 100
+	for i := 0; i < 10; i++ {
+		if _, err := client.Chat(ctx, chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat %d failed: %v", i, err)
+		}
+	}
+
+	_, err := client.Chat(ctx, chatRequest("llama3"))
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected the token budget to be exhausted, got %v", err)
+	}
+	if limitErr.RetryAfter > time.Second || limitErr.RetryAfter < 900*time.Millisecond {
+		t.Errorf("Expected to wait about 1s for the budget, got %v", limitErr.RetryAfter)
+	}
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	l := &rateLimiter{
+		opts:    RateLimitOptions{RequestsPerSecond: 1, Burst: 1, TokensPerMinute: 60},
+		buckets: make(map[string]*rateBucket),
+	}
+	now := time.Now()
+	l.swept = now
+
+	l.bucket("idle", now).requests--
+	l.bucket("busy", now).tokens -= 600
+	if len(l.buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", len(l.buckets))
+	}
+
+	// After a minute the idle key has refilled and is forgotten; the busy
+	// one still owes tokens
+	l.bucket("new", now.Add(rateSweepInterval))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("Expected the idle bucket to be dropped")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("Expected the overspent bucket to be kept")
+	}
+}
+
+func TestRateLimitStacked(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"ok"},"done":true,"prompt_eval_count":1,"eval_count":1}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL),
+		WithRateLimit(&RateLimitOptions{RequestsPerSecond: 20, NonBlocking: true}),
+		WithRateLimit(&RateLimitOptions{
+			RequestsPerSecond: 1,
+			Burst:             2,
+			KeyFunc:           func(context.Context) string { return "client" },
+			NonBlocking:       true,
+		}),
+	)
+
+	// Each tenant is within its own budget, but the client-wide one runs out
+	for _, tenant := range []string{"a", "b"} {
+		if _, err := client.Chat(ContextWithTenant(context.Background(), tenant), chatRequest("llama3")); err != nil {
+			t.Fatalf("Chat for %s failed: %v", tenant, err)
+		}
+	}
+	_, err := client.Chat(ContextWithTenant(context.Background(), "c"), chatRequest("llama3"))
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) || limitErr.Key != "client" {
+		t.Fatalf("Expected the client-wide budget to be exhausted, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected 2 requests to be sent, got %d", n)
+	}
+}
@@ -0,0 +1,171 @@
+package ollama
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultPinKeepAlive = 10 * time.Minute
+	defaultPinInterval  = time.Minute
+)
+
+// Load loads a model into memory and keeps it resident for keepAlive (a
+// negative value keeps it loaded until it is unloaded, zero uses the server's
+// default). It sends an empty request with the right keep_alive, using
+// /api/embed for embedding-only models and /api/generate for everything else.
+func (c *Client) Load(ctx context.Context, model string, keepAlive time.Duration) error {
+	if keepAlive == 0 {
+		// keep_alive 0 would unload the model again; leave it out instead
+		return c.setKeepAlive(ctx, model, nil)
+	}
+	return c.setKeepAlive(ctx, model, &Duration{Duration: keepAlive})
+}
+
+// Unload evicts a model from memory immediately.
+func (c *Client) Unload(ctx context.Context, model string) error {
+	unload := UnloadImmediately()
+	return c.setKeepAlive(ctx, model, &unload)
+}
+
+// setKeepAlive sends an empty request for model with keepAlive, or with the
+// server's default if keepAlive is nil.
+func (c *Client) setKeepAlive(ctx context.Context, model string, keepAlive *Duration) error {
+	if c.isEmbeddingModel(ctx, model) {
+		req := &EmbedRequest{Model: model, Input: []string{}}
+		if keepAlive != nil {
+			req.SetKeepAlive(*keepAlive)
+		}
+		_, err := c.Embed(ctx, req)
+		return err
+	}
+
+	req := &GenerateRequest{Model: model}
+	if keepAlive != nil {
+		req.SetKeepAlive(*keepAlive)
+	}
+	_, err := c.Generate(ctx, req)
+	return err
+}
+
+// isEmbeddingModel reports whether the model only supports embeddings.
+// Models whose capabilities cannot be read are treated as generative.
+func (c *Client) isEmbeddingModel(ctx context.Context, model string) bool {
+	capabilities, err := c.ModelCapabilities(ctx, model)
+	if err != nil {
+		return false
+	}
+
+	embedding, completion := false, false
+	for _, capability := range capabilities {
+		switch capability {
+		case CapabilityEmbedding:
+			embedding = true
+		case CapabilityCompletion:
+			completion = true
+		}
+	}
+	return embedding && !completion
+}
+
+// PinOptions configures PinModels.
+type PinOptions struct {
+	// KeepAlive is sent with every refresh (default 10m). A negative value
+	// keeps models loaded until they are unloaded; refreshing then only
+	// reloads models after a server restart or eviction.
+	KeepAlive time.Duration
+
+	// Interval between refreshes. Defaults to half of KeepAlive, or one
+	// minute when that is not positive.
+	Interval time.Duration
+}
+
+// PinStatus reports the residency of a pinned model after a refresh.
+type PinStatus struct {
+	Model string
+	// Loaded is true if the model shows up in Ps after the refresh.
+	Loaded    bool
+	ExpiresAt *time.Time
+	SizeVRAM  int64
+	// Err is the error from loading the model, if any.
+	Err error
+}
+
+// PinModels keeps a set of models resident by refreshing their keep-alive
+// periodically, starting immediately. After every refresh the status of all
+// models is sent on the returned channel, which is closed when ctx is done.
+// The channel must be drained.
+//
+// Example:
+//
+//	for statuses := range client.PinModels(ctx, []string{"llama3", "nomic-embed-text"}, nil) {
+//		for _, s := range statuses {
+//			fmt.Println(s.Model, s.Loaded, s.ExpiresAt)
+//		}
+//	}
+func (c *Client) PinModels(ctx context.Context, models []string, opts *PinOptions) <-chan []PinStatus {
+	statusChan := make(chan []PinStatus)
+
+	keepAlive := defaultPinKeepAlive
+	interval := time.Duration(0)
+	if opts != nil {
+		if opts.KeepAlive != 0 {
+			keepAlive = opts.KeepAlive
+		}
+		interval = opts.Interval
+	}
+	if interval <= 0 {
+		interval = keepAlive / 2
+	}
+	if interval <= 0 {
+		interval = defaultPinInterval
+	}
+
+	go func() {
+		defer close(statusChan)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			statuses := c.refreshPins(ctx, models, keepAlive)
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case statusChan <- statuses:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return statusChan
+}
+
+func (c *Client) refreshPins(ctx context.Context, models []string, keepAlive time.Duration) []PinStatus {
+	statuses := make([]PinStatus, len(models))
+	for i, model := range models {
+		statuses[i] = PinStatus{Model: model, Err: c.Load(ctx, model, keepAlive)}
+	}
+
+	ps, err := c.Ps(ctx)
+	if err != nil {
+		return statuses
+	}
+	for i := range statuses {
+		if running, ok := ps.Find(statuses[i].Model); ok {
+			statuses[i].Loaded = true
+			statuses[i].ExpiresAt = running.ExpiresAt
+			statuses[i].SizeVRAM = running.SizeVRAM
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,180 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadAndUnload(t *testing.T) {
+	var mu sync.Mutex
+	loaded := make(map[string]bool)
+	endpoints := make(map[string]string)
+	keepAlives := make(map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var model string
+		var keepAlive interface{}
+		switch r.URL.Path {
+		case "/api/show":
+			var req ShowRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			capabilities := []string{"completion"}
+			if req.Model == "nomic-embed-text" {
+				capabilities = []string{"embedding"}
+			}
+			_ = json.NewEncoder(w).Encode(ShowResponse{Capabilities: capabilities})
+			return
+		case "/api/generate":
+			var req GenerateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Prompt != "" {
+				t.Errorf("Expected empty prompt, got %q", req.Prompt)
+			}
+			model, keepAlive = req.Model, req.KeepAlive
+			_ = json.NewEncoder(w).Encode(GenerateResponse{Model: req.Model, Done: true})
+		case "/api/embed":
+			var req EmbedRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			model, keepAlive = req.Model, req.KeepAlive
+			_ = json.NewEncoder(w).Encode(EmbedResponse{Model: req.Model, Embeddings: [][]float64{}})
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		endpoints[model] = strings.TrimPrefix(r.URL.Path, "/api/")
+		keepAlives[model] = keepAlive
+		loaded[model] = keepAlive != float64(0)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	ctx := context.Background()
+
+	if err := client.Load(ctx, "llama3", 30*time.Minute); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := client.Load(ctx, "nomic-embed-text", -1); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if endpoints["llama3"] != "generate" || keepAlives["llama3"] != "30m0s" {
+		t.Errorf("Unexpected load of llama3: %s %v", endpoints["llama3"], keepAlives["llama3"])
+	}
+	if endpoints["nomic-embed-text"] != "embed" || keepAlives["nomic-embed-text"] != float64(-1) {
+		t.Errorf("Unexpected load of nomic-embed-text: %s %v", endpoints["nomic-embed-text"], keepAlives["nomic-embed-text"])
+	}
+
+	if err := client.Unload(ctx, "llama3"); err != nil {
+		t.Fatalf("Unload failed: %v", err)
+	}
+	if keepAlives["llama3"] != float64(0) {
+		t.Errorf("Expected keep_alive 0, got %v", keepAlives["llama3"])
+	}
+	if loaded["llama3"] {
+		t.Error("Expected llama3 to be unloaded")
+	}
+
+	// Zero leaves keep_alive to the server instead of unloading
+	if err := client.Load(ctx, "llama3", 0); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if keepAlives["llama3"] != nil {
+		t.Errorf("Expected no keep_alive, got %v", keepAlives["llama3"])
+	}
+	if !loaded["llama3"] {
+		t.Error("Expected llama3 to be loaded")
+	}
+}
+
+func TestPinModels(t *testing.T) {
+	var mu sync.Mutex
+	loaded := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			var req ShowRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			capabilities := []string{"completion"}
+			if req.Model == "nomic-embed-text" {
+				capabilities = []string{"embedding"}
+			}
+			_ = json.NewEncoder(w).Encode(ShowResponse{Capabilities: capabilities})
+		case "/api/generate":
+			var req GenerateRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			loaded[req.Model] = true
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(GenerateResponse{Model: req.Model, Done: true})
+		case "/api/embed":
+			var req EmbedRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			loaded[req.Model] = true
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(EmbedResponse{Model: req.Model, Embeddings: [][]float64{}})
+		case "/api/ps":
+			expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			var response ProcessResponse
+			mu.Lock()
+			for model := range loaded {
+				response.Models = append(response.Models, ProcessModel{Name: model + ":latest", Model: model + ":latest", ExpiresAt: &expires, SizeVRAM: 1024})
+			}
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(response)
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statusChan := client.PinModels(ctx, []string{"llama3", "nomic-embed-text"}, &PinOptions{
+		KeepAlive: time.Minute,
+		Interval:  10 * time.Millisecond,
+	})
+
+	for round := 0; round < 2; round++ {
+		select {
+		case statuses := <-statusChan:
+			if len(statuses) != 2 {
+				t.Fatalf("Expected 2 statuses, got %d", len(statuses))
+			}
+			for _, s := range statuses {
+				if s.Err != nil || !s.Loaded || s.ExpiresAt == nil || s.SizeVRAM != 1024 {
+					t.Errorf("Unexpected status: %+v", s)
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for pin status")
+		}
+	}
+
+	cancel()
+	for range statusChan {
+	}
+
+	// A keep-alive too short to halve falls back to the default interval
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	statusChan = client.PinModels(ctx, []string{"llama3"}, &PinOptions{KeepAlive: time.Nanosecond})
+	select {
+	case <-statusChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for pin status")
+	}
+	cancel()
+	for range statusChan {
+	}
+}
@@ -0,0 +1,177 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// errIncompleteStream is returned when a progress stream ends without the
+// final "success" status, which happens when the connection is dropped.
+var errIncompleteStream = errors.New("stream ended before success")
+
+// RetryOptions configures PullWithRetry.
+type RetryOptions struct {
+	// MaxAttempts limits the number of requests, including the first one.
+	// Zero means no limit other than MaxDuration.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry (default 1s). It
+	// doubles after every failed attempt, up to MaxBackoff (default 30s).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// MaxDuration bounds the whole operation across all attempts.
+	// Zero means no limit other than the context.
+	MaxDuration time.Duration
+
+	// OnRetry is called before waiting to retry after a failed attempt.
+	OnRetry func(attempt int, err error)
+}
+
+// PullWithRetry pulls a model like PullStream, but when the connection drops,
+// the stream ends early or the server answers with a 5xx or 429 status it
+// re-issues the pull after a backoff. The server resumes from the partially
+// downloaded blobs, so progress continues where it stopped and the returned
+// channels describe one logical pull.
+//
+// Errors the server reports in the stream, such as an unknown model, and
+// other 4xx statuses are not retried.
+//
+// Example:
+//
+//	progressChan, errorChan := client.PullWithRetry(ctx, &ollama.PullRequest{Model: "llama3:70b"},
+//		&ollama.RetryOptions{MaxDuration: 2 * time.Hour})
+//	tracker := ollama.NewProgressTracker()
+//	tracker.Track(progressChan, func(ev ollama.ProgressEvent) {
+//		fmt.Printf("\r%.1f%%", ev.Percent)
+//	})
+//	if err := <-errorChan; err != nil {
+//		log.Fatal(err)
+//	}
+func (c *Client) PullWithRetry(ctx context.Context, req *PullRequest, opts *RetryOptions) (<-chan *ProgressResponse, <-chan error) {
+	responseChan := make(chan *ProgressResponse)
+	errorChan := make(chan error, 1)
+
+	if opts == nil {
+		opts = &RetryOptions{}
+	}
+
+	go func() {
+		defer close(responseChan)
+		defer close(errorChan)
+
+		if opts.MaxDuration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+			defer cancel()
+		}
+
+		backoff := opts.InitialBackoff
+		if backoff <= 0 {
+			backoff = time.Second
+		}
+		maxBackoff := opts.MaxBackoff
+		if maxBackoff <= 0 {
+			maxBackoff = 30 * time.Second
+		}
+
+		for attempt := 1; ; attempt++ {
+			err := c.pullAttempt(ctx, req, responseChan)
+			if err == nil {
+				return
+			}
+
+			if ctx.Err() != nil {
+				errorChan <- fmt.Errorf("pull %s: %w (last error: %v)", req.Model, ctx.Err(), err)
+				return
+			}
+			if !isRetryable(err) || (opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts) {
+				errorChan <- fmt.Errorf("pull %s failed after %d attempt(s): %w", req.Model, attempt, err)
+				return
+			}
+
+			if opts.OnRetry != nil {
+				opts.OnRetry(attempt, err)
+			}
+
+			// Full jitter between half and the whole backoff
+			delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				errorChan <- fmt.Errorf("pull %s: %w (last error: %v)", req.Model, ctx.Err(), err)
+				return
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}()
+
+	return responseChan, errorChan
+}
+
+// pullAttempt runs a single streaming pull, forwarding frames to out. It
+// returns nil only if the stream reached the "success" status.
+func (c *Client) pullAttempt(ctx context.Context, req *PullRequest, out chan<- *ProgressResponse) error {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progressChan, errChan := c.PullStream(attemptCtx, req)
+	drain := func() {
+		cancel()
+		for range progressChan {
+		}
+	}
+
+	success := false
+	for progress := range progressChan {
+		if progress.Error != "" {
+			// The server gave up on the pull, e.g. for an unknown model
+			drain()
+			return &ResponseError{StatusCode: http.StatusOK, Message: progress.Error}
+		}
+		if progress.Status == "success" {
+			success = true
+		}
+
+		select {
+		case out <- progress:
+		case <-ctx.Done():
+			drain()
+			return ctx.Err()
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		return err
+	}
+	if !success {
+		return errIncompleteStream
+	}
+	return nil
+}
+
+// isRetryable reports whether err is worth retrying: transport failures,
+// interrupted streams, server errors and rate limiting. Error frames in a
+// stream are reported as a *ResponseError with status 200 and are not.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500 ||
+			respErr.StatusCode == http.StatusRequestTimeout ||
+			respErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
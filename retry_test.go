@@ -0,0 +1,149 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPullWithRetryResumes(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		switch atomic.AddInt32(&attempts, 1) {
+		case 1:
+			// Connection dropped in the middle of a frame
+			_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 100, Completed: 30})
+			_, _ = w.Write([]byte(`{"status":"pulling abc","dig`))
+		case 2:
+			// Connection dropped before success
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 100, Completed: 60})
+		case 3:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_ = enc.Encode(ProgressResponse{Status: "pulling abc", Digest: "sha256:abc", Total: 100, Completed: 100})
+			_ = enc.Encode(ProgressResponse{Status: "verifying sha256 digest"})
+			_ = enc.Encode(ProgressResponse{Status: "success"})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	var retries []error
+	progressChan, errorChan := client.PullWithRetry(context.Background(), &PullRequest{Model: "llama3"}, &RetryOptions{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		OnRetry:        func(attempt int, err error) { retries = append(retries, err) },
+	})
+
+	final := NewProgressTracker().Track(progressChan, nil)
+	if err := <-errorChan; err != nil {
+		t.Fatalf("PullWithRetry failed: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&attempts); n != 4 {
+		t.Errorf("Expected 4 attempts, got %d", n)
+	}
+	if len(retries) != 3 {
+		t.Fatalf("Expected 3 retries, got %d", len(retries))
+	}
+	if !errors.Is(retries[1], errIncompleteStream) {
+		t.Errorf("Expected incomplete stream error, got %v", retries[1])
+	}
+	if final.Phase != PhaseSuccess || final.Percent != 100 {
+		t.Errorf("Unexpected final progress: %+v", final)
+	}
+}
+
+func TestPullWithRetryStopsOnClientError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "pull model manifest: file does not exist"})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	progressChan, errorChan := client.PullWithRetry(context.Background(), &PullRequest{Model: "missing"}, &RetryOptions{
+		InitialBackoff: time.Millisecond,
+	})
+	for range progressChan {
+	}
+
+	err := <-errorChan
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected 404 ResponseError, got %v", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("Expected 1 attempt, got %d", n)
+	}
+}
+
+func TestPullWithRetryStopsOnErrorFrame(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// How the server reports an unknown model
+		atomic.AddInt32(&attempts, 1)
+		enc := json.NewEncoder(w)
+		_ = enc.Encode(ProgressResponse{Status: "pulling manifest"})
+		_ = enc.Encode(ErrorResponse{Error: "pull model manifest: file does not exist"})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	progressChan, errorChan := client.PullWithRetry(context.Background(), &PullRequest{Model: "missing"}, &RetryOptions{
+		InitialBackoff: time.Millisecond,
+	})
+	for range progressChan {
+	}
+
+	err := <-errorChan
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.Message != "pull model manifest: file does not exist" {
+		t.Fatalf("Expected the error frame as a ResponseError, got %v", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("Expected 1 attempt, got %d", n)
+	}
+}
+
+func TestPullWithRetryLimits(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+
+	_, errorChan := client.PullWithRetry(context.Background(), &PullRequest{Model: "llama3"}, &RetryOptions{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	})
+	if err := <-errorChan; err == nil {
+		t.Fatal("Expected error after max attempts")
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+
+	_, errorChan = client.PullWithRetry(context.Background(), &PullRequest{Model: "llama3"}, &RetryOptions{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxDuration:    50 * time.Millisecond,
+	})
+	if err := <-errorChan; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultSchedulerMaxConcurrent = 4
+
+// ErrQueueFull is returned when a request cannot be queued because the queue
+// for its model and priority is at SchedulerOptions.MaxQueue.
+var ErrQueueFull = errors.New("ollama: request queue is full")
+
+// Priority is the scheduling class of a request. Lower values are served
+// first; requests without a priority are interactive.
+type Priority int
+
+// Priority classes.
+const (
+	PriorityInteractive Priority = 0
+	PriorityBatch       Priority = 10
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	}
+	return strconv.Itoa(int(p))
+}
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a context whose requests are scheduled with
+// the given priority.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority carried by ctx, or
+// PriorityInteractive.
+func PriorityFromContext(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey{}).(Priority)
+	return priority
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a context whose requests are attributed to
+// tenant, e.g. a user or team, for fair scheduling.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, or "".
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// SchedulerOptions configures a Scheduler. Zero values use the defaults.
+type SchedulerOptions struct {
+	// MaxConcurrent is the number of requests per model sent at the same
+	// time (default 4). Streams hold their slot until they are read to the
+	// end or closed.
+	MaxConcurrent int
+	// ModelLimits overrides MaxConcurrent for some models, e.g.
+	// {"llama3:70b": 1}.
+	ModelLimits map[string]int
+
+	// MaxQueue limits the number of requests waiting per model and priority
+	// (default: no limit). Requests over the limit fail with ErrQueueFull.
+	MaxQueue int
+
+	// OnWait is called when a request is sent, with the time it waited in
+	// the queue.
+	OnWait func(model string, priority Priority, tenant string, wait time.Duration)
+}
+
+// Scheduler limits the number of chat, generate and embed requests sent per
+// model and queues the rest. Queued requests are served by priority, and
+// within a priority in turn across tenants, so one tenant's batch cannot
+// starve the others. Other requests are not scheduled.
+//
+// Limits apply to everything sent through the client, across all hosts of a
+// Pool.
+//
+// Example:
+//
+//	scheduler := ollama.NewScheduler(&ollama.SchedulerOptions{
+//		MaxConcurrent: 2,
+//		MaxQueue:      100,
+//	})
+//	client, err := ollama.NewClient(ollama.WithMiddleware(scheduler.Middleware()))
+//	...
+//	ctx = ollama.ContextWithPriority(ctx, ollama.PriorityBatch)
+//	ctx = ollama.ContextWithTenant(ctx, "reports")
+//	resp, err := client.Chat(ctx, req)
+type Scheduler struct {
+	opts SchedulerOptions
+
+	mu     sync.Mutex
+	models map[string]*modelQueue
+}
+
+// QueueStats describes the scheduling state of a model.
+type QueueStats struct {
+	Model   string
+	Limit   int
+	Running int
+	// Queued is the number of waiting requests per priority.
+	Queued map[Priority]int
+}
+
+type modelQueue struct {
+	limit   int
+	running int
+	classes map[Priority]*tenantQueue
+}
+
+// tenantQueue holds the waiting requests of one priority, per tenant.
+// Tenants are served in turn.
+type tenantQueue struct {
+	tenants []string
+	waiting map[string][]*waiter
+	size    int
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewScheduler creates a Scheduler.
+func NewScheduler(opts *SchedulerOptions) *Scheduler {
+	var o SchedulerOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxConcurrent <= 0 {
+		o.MaxConcurrent = defaultSchedulerMaxConcurrent
+	}
+	limits := make(map[string]int, len(o.ModelLimits))
+	for model, limit := range o.ModelLimits {
+		limits[modelKey(model)] = limit
+	}
+	o.ModelLimits = limits
+
+	return &Scheduler{opts: o, models: make(map[string]*modelQueue)}
+}
+
+// Middleware returns the Middleware that schedules requests.
+func (s *Scheduler) Middleware() Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(ctx context.Context, req *Request) (*http.Response, error) {
+			if !loadsModel(req.Endpoint) {
+				return next(ctx, req)
+			}
+
+			model := modelKey(req.Model())
+			priority := PriorityFromContext(ctx)
+			tenant := TenantFromContext(ctx)
+
+			start := time.Now()
+			if err := s.acquire(ctx, model, priority, tenant); err != nil {
+				return nil, err
+			}
+			if s.opts.OnWait != nil {
+				s.opts.OnWait(model, priority, tenant, time.Since(start))
+			}
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				s.release(model)
+				return nil, err
+			}
+			ObserveResponse(resp, false, func(ResponseStats) {
+				s.release(model)
+			})
+			return resp, nil
+		}
+	}
+}
+
+// Stats returns the scheduling state of every model seen, sorted by name.
+func (s *Scheduler) Stats() []QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]QueueStats, 0, len(s.models))
+	for _, model := range sortedKeys(s.models) {
+		q := s.models[model]
+		queued := make(map[Priority]int)
+		for priority, class := range q.classes {
+			if class.size > 0 {
+				queued[priority] = class.size
+			}
+		}
+		stats = append(stats, QueueStats{Model: model, Limit: q.limit, Running: q.running, Queued: queued})
+	}
+	return stats
+}
+
+// acquire waits for a slot for model.
+func (s *Scheduler) acquire(ctx context.Context, model string, priority Priority, tenant string) error {
+	s.mu.Lock()
+	q := s.queue(model)
+	if q.running < q.limit && q.size() == 0 {
+		q.running++
+		s.mu.Unlock()
+		return nil
+	}
+
+	class := q.class(priority)
+	if s.opts.MaxQueue > 0 && class.size >= s.opts.MaxQueue {
+		s.mu.Unlock()
+		return ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{})}
+	class.push(tenant, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// The slot was handed over while giving up: pass it on
+		s.handOff(q)
+	} else {
+		class.remove(tenant, w)
+	}
+	return ctx.Err()
+}
+
+// release frees a slot of model, handing it to the next waiting request.
+func (s *Scheduler) release(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handOff(s.models[model])
+}
+
+// handOff gives a running slot to the next waiting request, or frees it.
+// s.mu must be held.
+func (s *Scheduler) handOff(q *modelQueue) {
+	priorities := make([]Priority, 0, len(q.classes))
+	for priority, class := range q.classes {
+		if class.size > 0 {
+			priorities = append(priorities, priority)
+		}
+	}
+	if len(priorities) == 0 {
+		q.running--
+		return
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+
+	w := q.classes[priorities[0]].pop()
+	w.granted = true
+	close(w.ready)
+}
+
+// queue returns the queue of model, creating it. s.mu must be held.
+func (s *Scheduler) queue(model string) *modelQueue {
+	q, ok := s.models[model]
+	if !ok {
+		limit, ok := s.opts.ModelLimits[model]
+		if !ok || limit <= 0 {
+			limit = s.opts.MaxConcurrent
+		}
+		q = &modelQueue{limit: limit, classes: make(map[Priority]*tenantQueue)}
+		s.models[model] = q
+	}
+	return q
+}
+
+func (q *modelQueue) size() int {
+	n := 0
+	for _, class := range q.classes {
+		n += class.size
+	}
+	return n
+}
+
+func (q *modelQueue) class(priority Priority) *tenantQueue {
+	class, ok := q.classes[priority]
+	if !ok {
+		class = &tenantQueue{waiting: make(map[string][]*waiter)}
+		q.classes[priority] = class
+	}
+	return class
+}
+
+func (t *tenantQueue) push(tenant string, w *waiter) {
+	if len(t.waiting[tenant]) == 0 {
+		t.tenants = append(t.tenants, tenant)
+	}
+	t.waiting[tenant] = append(t.waiting[tenant], w)
+	t.size++
+}
+
+// pop removes the first request of the next tenant, which then moves to the
+// back of the line.
+func (t *tenantQueue) pop() *waiter {
+	tenant := t.tenants[0]
+	t.tenants = t.tenants[1:]
+
+	waiting := t.waiting[tenant]
+	w := waiting[0]
+	if len(waiting) > 1 {
+		t.waiting[tenant] = waiting[1:]
+		t.tenants = append(t.tenants, tenant)
+	} else {
+		delete(t.waiting, tenant)
+	}
+	t.size--
+	return w
+}
+
+func (t *tenantQueue) remove(tenant string, w *waiter) {
+	waiting := t.waiting[tenant]
+	for i, candidate := range waiting {
+		if candidate != w {
+			continue
+		}
+		waiting = append(waiting[:i:i], waiting[i+1:]...)
+		t.size--
+		break
+	}
+	if len(waiting) > 0 {
+		t.waiting[tenant] = waiting
+		return
+	}
+
+	delete(t.waiting, tenant)
+	for i, name := range t.tenants {
+		if name == tenant {
+			t.tenants = append(t.tenants[:i:i], t.tenants[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func queued(s *Scheduler) int {
+	n := 0
+	for _, stats := range s.Stats() {
+		for _, count := range stats.Queued {
+			n += count
+		}
+	}
+	return n
+}
+
+func userChat(content string) *ChatRequest {
+	return &ChatRequest{Model: "llama3", Messages: []Message{{Role: "user", Content: content}}}
+}
+
+func TestSchedulerConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			_ = json.NewEncoder(w).Encode(ListResponse{})
+			return
+		}
+
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: "llama3", Message: Message{Content: "ok"}, Done: true})
+	}))
+	defer server.Close()
+
+	scheduler := NewScheduler(&SchedulerOptions{ModelLimits: map[string]int{"llama3:latest": 2}})
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(scheduler.Middleware()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Chat(context.Background(), userChat("hi")); err != nil {
+				t.Errorf("Chat failed: %v", err)
+			}
+		}()
+	}
+
+	waitFor(t, "queued requests", func() bool { return queued(scheduler) == 3 })
+	stats := scheduler.Stats()
+	if len(stats) != 1 || stats[0].Model != "llama3:latest" || stats[0].Limit != 2 || stats[0].Running != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	// Other endpoints are not scheduled
+	if _, err := client.List(context.Background()); err != nil {
+		t.Errorf("List failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		release <- struct{}{}
+	}
+	wg.Wait()
+
+	if maxActive != 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", maxActive)
+	}
+	if stats := scheduler.Stats(); stats[0].Running != 0 {
+		t.Errorf("Expected every slot to be released, got %+v", stats)
+	}
+}
+
+func TestSchedulerPriorityAndFairness(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		order = append(order, req.Messages[0].Content)
+		mu.Unlock()
+
+		<-release
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: req.Model, Message: Message{Content: "ok"}, Done: true})
+	}))
+	defer server.Close()
+	arrived := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+
+	var waits []string
+	scheduler := NewScheduler(&SchedulerOptions{
+		MaxConcurrent: 1,
+		OnWait: func(model string, priority Priority, tenant string, wait time.Duration) {
+			mu.Lock()
+			waits = append(waits, priority.String()+"/"+tenant)
+			mu.Unlock()
+		},
+	})
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(scheduler.Middleware()))
+
+	var wg sync.WaitGroup
+	send := func(content string, priority Priority, tenant string) {
+		ctx := ContextWithTenant(ContextWithPriority(context.Background(), priority), tenant)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Chat(ctx, userChat(content)); err != nil {
+				t.Errorf("Chat failed: %v", err)
+			}
+		}()
+	}
+
+	send("first", PriorityInteractive, "")
+	waitFor(t, "the first request", func() bool { return len(arrived()) == 1 })
+
+	// Queued in this order, one at a time
+	for i, r := range []struct {
+		content  string
+		priority Priority
+		tenant   string
+	}{
+		{"batch-a1", PriorityBatch, "a"},
+		{"batch-a2", PriorityBatch, "a"},
+		{"batch-a3", PriorityBatch, "a"},
+		{"batch-b1", PriorityBatch, "b"},
+		{"interactive", PriorityInteractive, "c"},
+	} {
+		send(r.content, r.priority, r.tenant)
+		n := i + 1
+		waitFor(t, "queued requests", func() bool { return queued(scheduler) == n })
+	}
+
+	for i := 0; i < 6; i++ {
+		release <- struct{}{}
+	}
+	wg.Wait()
+
+	want := []string{"first", "interactive", "batch-a1", "batch-b1", "batch-a2", "batch-a3"}
+	if got := arrived(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected order:\n got %v\nwant %v", got, want)
+	}
+	if len(waits) != 6 || waits[0] != "interactive/" || waits[1] != "interactive/c" || waits[2] != "batch/a" {
+		t.Errorf("Unexpected OnWait calls %v", waits)
+	}
+}
+
+func TestSchedulerQueueFullAndCancel(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		order = append(order, req.Messages[0].Content)
+		mu.Unlock()
+
+		<-release
+		_ = json.NewEncoder(w).Encode(ChatResponse{Model: req.Model, Message: Message{Content: "ok"}, Done: true})
+	}))
+	defer server.Close()
+	arrived := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+
+	scheduler := NewScheduler(&SchedulerOptions{MaxConcurrent: 1, MaxQueue: 1})
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(scheduler.Middleware()))
+
+	// A stream holds its slot until it is read to the end
+	responseChan, errorChan := client.ChatStream(context.Background(), userChat("stream"))
+	waitFor(t, "the stream", func() bool { return len(arrived()) == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queuedErr := make(chan error, 1)
+	go func() {
+		_, err := client.Chat(ctx, userChat("canceled"))
+		queuedErr <- err
+	}()
+	waitFor(t, "the queued request", func() bool { return queued(scheduler) == 1 })
+
+	if _, err := client.Chat(context.Background(), userChat("rejected")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	cancel()
+	if err := <-queuedErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the queued request to be canceled, got %v", err)
+	}
+	if queued(scheduler) != 0 {
+		t.Error("Expected the canceled request to leave the queue")
+	}
+
+	release <- struct{}{}
+	for range responseChan {
+	}
+	if err := <-errorChan; err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+
+	waitFor(t, "the slot to be released", func() bool { return scheduler.Stats()[0].Running == 0 })
+	if got := arrived(); !reflect.DeepEqual(got, []string{"stream"}) {
+		t.Errorf("Expected rejected and canceled requests not to be sent, got %v", got)
+	}
+}
+
+func TestSchedulerReleasesAbandonedStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// More frames than the client buffers, then nothing until the
+		// client goes away
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for i := 0; i < 500; i++ {
+			_ = enc.Encode(ChatResponse{Model: "llama3", Message: Message{Role: "assistant", Content: "x"}})
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	scheduler := NewScheduler(&SchedulerOptions{MaxConcurrent: 1})
+	client, _ := NewClient(WithHost(server.URL), WithMiddleware(scheduler.Middleware()))
+
+	// The consumer reads one frame and gives up without draining
+	ctx, cancel := context.WithCancel(context.Background())
+	responseChan, _ := client.ChatStream(ctx, userChat("abandoned"))
+	<-responseChan
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	waitFor(t, "the slot to be released", func() bool { return scheduler.Stats()[0].Running == 0 })
+}
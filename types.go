@@ -55,6 +55,76 @@ func (i Image) MarshalJSON() ([]byte, error) {
 	return json.Marshal(i.Data)
 }
 
+// Duration is a keep-alive duration as understood by the server.
+// A negative duration keeps the model loaded forever and zero unloads it
+// immediately. It can be used as the KeepAlive of any request.
+type Duration struct {
+	time.Duration
+}
+
+// KeepForever returns a keep-alive that keeps the model loaded until it is
+// unloaded explicitly.
+func KeepForever() Duration {
+	return Duration{Duration: -1}
+}
+
+// UnloadImmediately returns a keep-alive that unloads the model as soon as
+// the request completes.
+func UnloadImmediately() Duration {
+	return Duration{}
+}
+
+// MarshalJSON implements json.Marshaler for Duration
+func (d Duration) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.Duration < 0:
+		return []byte("-1"), nil
+	case d.Duration == 0:
+		return []byte("0"), nil
+	}
+	return json.Marshal(d.Duration.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Duration. It accepts a number
+// of seconds or a Go duration string such as "5m".
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case float64:
+		d.Duration = time.Duration(t * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(t)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", t, err)
+		}
+		d.Duration = parsed
+	default:
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+
+	if d.Duration < 0 {
+		d.Duration = -1
+	}
+	return nil
+}
+
+// SetKeepAlive sets the keep-alive of the request to d. Prefer it to
+// assigning KeepAlive directly, which accepts any value for compatibility.
+func (r *GenerateRequest) SetKeepAlive(d Duration) { r.KeepAlive = d }
+
+// SetKeepAlive sets the keep-alive of the request to d.
+func (r *ChatRequest) SetKeepAlive(d Duration) { r.KeepAlive = d }
+
+// SetKeepAlive sets the keep-alive of the request to d.
+func (r *EmbedRequest) SetKeepAlive(d Duration) { r.KeepAlive = d }
+
+// SetKeepAlive sets the keep-alive of the request to d.
+func (r *EmbeddingsRequest) SetKeepAlive(d Duration) { r.KeepAlive = d }
+
 // ToolCall represents a function call made by the model.
 // Used when the model decides to call a tool/function during conversation.
 type ToolCall struct {
@@ -121,6 +191,10 @@ type Options struct {
 	MirostatEta      *float64 `json:"mirostat_eta,omitempty"`
 	PenalizeNewline  *bool    `json:"penalize_newline,omitempty"`
 	Stop             []string `json:"stop,omitempty"`
+
+	// Extra holds parameters without a field above, such as ones added by a
+	// newer server, by name. They are sent along with the other options.
+	Extra map[string]interface{} `json:"-"`
 }
 
 // GenerateRequest represents a generation request
@@ -136,7 +210,7 @@ type GenerateRequest struct {
 	Format    interface{} `json:"format,omitempty"`
 	Options   *Options    `json:"options,omitempty"`
 	Images    []Image     `json:"images,omitempty"`
-	KeepAlive interface{} `json:"keep_alive,omitempty"`
+	KeepAlive interface{} `json:"keep_alive,omitempty"` // Duration, string or number of seconds; see SetKeepAlive
 	Think     *bool       `json:"think,omitempty"`
 }
 
@@ -165,7 +239,7 @@ type ChatRequest struct {
 	Stream    *bool       `json:"stream,omitempty"`
 	Format    interface{} `json:"format,omitempty"`
 	Options   *Options    `json:"options,omitempty"`
-	KeepAlive interface{} `json:"keep_alive,omitempty"`
+	KeepAlive interface{} `json:"keep_alive,omitempty"` // Duration, string or number of seconds; see SetKeepAlive
 	Think     *bool       `json:"think,omitempty"`
 }
 
@@ -186,11 +260,12 @@ type ChatResponse struct {
 
 // EmbedRequest represents an embedding request
 type EmbedRequest struct {
-	Model     string      `json:"model"`
-	Input     interface{} `json:"input"` // string or []string
-	Truncate  *bool       `json:"truncate,omitempty"`
-	Options   *Options    `json:"options,omitempty"`
-	KeepAlive interface{} `json:"keep_alive,omitempty"`
+	Model      string      `json:"model"`
+	Input      interface{} `json:"input"` // string or []string
+	Truncate   *bool       `json:"truncate,omitempty"`
+	Dimensions int         `json:"dimensions,omitempty"`
+	Options    *Options    `json:"options,omitempty"`
+	KeepAlive  interface{} `json:"keep_alive,omitempty"` // Duration, string or number of seconds; see SetKeepAlive
 }
 
 // EmbedResponse represents an embedding response
@@ -208,7 +283,7 @@ type EmbeddingsRequest struct {
 	Model     string      `json:"model"`
 	Prompt    string      `json:"prompt,omitempty"`
 	Options   *Options    `json:"options,omitempty"`
-	KeepAlive interface{} `json:"keep_alive,omitempty"`
+	KeepAlive interface{} `json:"keep_alive,omitempty"` // Duration, string or number of seconds; see SetKeepAlive
 }
 
 // EmbeddingsResponse represents an embeddings response (legacy)
@@ -323,6 +398,7 @@ type ProgressResponse struct {
 	Digest    string `json:"digest,omitempty"`
 	Total     int64  `json:"total,omitempty"`
 	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
 // StatusResponse represents a simple status response
@@ -0,0 +1,165 @@
+package ollama
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultWatchInterval is used by WatchPs for a non-positive interval.
+const defaultWatchInterval = 5 * time.Second
+
+// PsEventType identifies a change between two /api/ps snapshots.
+type PsEventType string
+
+// Events emitted by WatchPs.
+const (
+	// ModelLoaded is emitted when a model appears in the running list,
+	// including models already running when watching starts.
+	ModelLoaded PsEventType = "loaded"
+	// ModelUnloaded is emitted when a model disappears from the running list.
+	ModelUnloaded PsEventType = "unloaded"
+	// ModelExpiryExtended is emitted when a model's ExpiresAt moves later,
+	// i.e. it served a request or its keep-alive was refreshed.
+	ModelExpiryExtended PsEventType = "expiry_extended"
+	// VRAMChanged is emitted when a model's SizeVRAM changes.
+	VRAMChanged PsEventType = "vram_changed"
+	// PsPollError is emitted when polling fails; watching continues.
+	PsPollError PsEventType = "error"
+)
+
+// PsEvent describes a change in the set of running models.
+type PsEvent struct {
+	Type PsEventType
+	// Model is the current state, or the last known state for ModelUnloaded.
+	Model ProcessModel
+	// Previous is the state before the change, nil for ModelLoaded.
+	Previous *ProcessModel
+	At       time.Time
+	// Err is set for PsPollError events.
+	Err error
+}
+
+// WatchPs polls /api/ps every interval and emits an event for every change
+// between successive snapshots; a non-positive interval polls every five
+// seconds. The channel is closed when ctx is done and must be drained.
+//
+// Example:
+//
+//	for ev := range client.WatchPs(ctx, 5*time.Second) {
+//		switch ev.Type {
+//		case ollama.ModelLoaded:
+//			fmt.Println("loaded", ev.Model.Name, ev.Model.SizeVRAM)
+//		case ollama.ModelUnloaded:
+//			fmt.Println("unloaded", ev.Model.Name)
+//		}
+//	}
+func (c *Client) WatchPs(ctx context.Context, interval time.Duration) <-chan PsEvent {
+	eventChan := make(chan PsEvent)
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	go func() {
+		defer close(eventChan)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		previous := &ProcessResponse{}
+		for {
+			var events []PsEvent
+			current, err := c.Ps(ctx)
+			now := time.Now()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				events = []PsEvent{{Type: PsPollError, At: now, Err: err}}
+			} else {
+				events = DiffProcesses(previous, current, now)
+				previous = current
+			}
+
+			for _, ev := range events {
+				select {
+				case eventChan <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return eventChan
+}
+
+// DiffProcesses compares two /api/ps snapshots and returns the changes, with
+// unloads first and then loads and updates ordered by model name.
+func DiffProcesses(previous, current *ProcessResponse, at time.Time) []PsEvent {
+	before := processesByName(previous)
+	after := processesByName(current)
+
+	var events []PsEvent
+	for _, name := range sortedKeys(before) {
+		if _, ok := after[name]; !ok {
+			prev := before[name]
+			events = append(events, PsEvent{Type: ModelUnloaded, Model: prev, Previous: &prev, At: at})
+		}
+	}
+
+	for _, name := range sortedKeys(after) {
+		model := after[name]
+		prev, ok := before[name]
+		if !ok || (prev.Digest != "" && model.Digest != "" && prev.Digest != model.Digest) {
+			// A different digest under the same name is a reload
+			if ok {
+				events = append(events, PsEvent{Type: ModelUnloaded, Model: prev, Previous: &prev, At: at})
+			}
+			events = append(events, PsEvent{Type: ModelLoaded, Model: model, At: at})
+			continue
+		}
+
+		if prev.ExpiresAt != nil && model.ExpiresAt != nil && model.ExpiresAt.After(*prev.ExpiresAt) {
+			events = append(events, PsEvent{Type: ModelExpiryExtended, Model: model, Previous: &prev, At: at})
+		}
+		if prev.SizeVRAM != model.SizeVRAM {
+			events = append(events, PsEvent{Type: VRAMChanged, Model: model, Previous: &prev, At: at})
+		}
+	}
+
+	return events
+}
+
+func processesByName(ps *ProcessResponse) map[string]ProcessModel {
+	models := make(map[string]ProcessModel)
+	if ps == nil {
+		return models
+	}
+	for _, m := range ps.Models {
+		name := m.Model
+		if name == "" {
+			name = m.Name
+		}
+		if ref, err := ParseModelRef(name); err == nil {
+			name = ref.String()
+		}
+		models[name] = m
+	}
+	return models
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
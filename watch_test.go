@@ -0,0 +1,135 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiffProcesses(t *testing.T) {
+	t0 := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(5 * time.Minute)
+	now := time.Now()
+
+	previous := &ProcessResponse{Models: []ProcessModel{
+		{Model: "llama3:latest", Digest: "a", ExpiresAt: &t0, SizeVRAM: 100},
+		{Model: "qwen2:7b", Digest: "b", ExpiresAt: &t0, SizeVRAM: 200},
+		{Model: "gemma3:latest", Digest: "c", ExpiresAt: &t0, SizeVRAM: 300},
+	}}
+	current := &ProcessResponse{Models: []ProcessModel{
+		{Model: "llama3:latest", Digest: "a", ExpiresAt: &t1, SizeVRAM: 150},
+		{Model: "gemma3:latest", Digest: "c", ExpiresAt: &t0, SizeVRAM: 300},
+		{Model: "mistral:latest", Digest: "d", ExpiresAt: &t1, SizeVRAM: 400},
+	}}
+
+	events := DiffProcesses(previous, current, now)
+
+	expected := []struct {
+		typ   PsEventType
+		model string
+	}{
+		{ModelUnloaded, "qwen2:7b"},
+		{ModelExpiryExtended, "llama3:latest"},
+		{VRAMChanged, "llama3:latest"},
+		{ModelLoaded, "mistral:latest"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		if events[i].Type != e.typ || events[i].Model.Model != e.model {
+			t.Errorf("Event %d: expected %s %s, got %s %s", i, e.typ, e.model, events[i].Type, events[i].Model.Model)
+		}
+		if !events[i].At.Equal(now) {
+			t.Errorf("Event %d has wrong timestamp", i)
+		}
+	}
+	if events[2].Previous == nil || events[2].Previous.SizeVRAM != 100 {
+		t.Errorf("Expected previous VRAM 100, got %+v", events[2].Previous)
+	}
+
+	if len(DiffProcesses(current, current, now)) != 0 {
+		t.Error("Expected no events for identical snapshots")
+	}
+
+	reloaded := &ProcessResponse{Models: []ProcessModel{{Model: "gemma3", Digest: "z"}}}
+	events = DiffProcesses(&ProcessResponse{Models: current.Models[1:2]}, reloaded, now)
+	if len(events) != 2 || events[0].Type != ModelUnloaded || events[1].Type != ModelLoaded {
+		t.Errorf("Expected a reload to be reported as unload and load, got %+v", events)
+	}
+}
+
+func TestWatchPs(t *testing.T) {
+	var polls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response ProcessResponse
+		switch atomic.AddInt32(&polls, 1) {
+		case 1:
+			response.Models = []ProcessModel{{Model: "llama3:latest", SizeVRAM: 100}}
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case 3:
+			response.Models = []ProcessModel{{Model: "llama3:latest", SizeVRAM: 100}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventChan := client.WatchPs(ctx, 5*time.Millisecond)
+
+	var types []PsEventType
+	for len(types) < 3 {
+		select {
+		case ev := <-eventChan:
+			types = append(types, ev.Type)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for events, got %v", types)
+		}
+	}
+
+	expected := []PsEventType{ModelLoaded, PsPollError, ModelUnloaded}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Expected events %v, got %v", expected, types)
+			break
+		}
+	}
+
+	cancel()
+	for range eventChan {
+	}
+}
+
+func TestWatchPsZeroInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProcessResponse{Models: []ProcessModel{{Model: "llama3:latest"}}})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(WithHost(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventChan := client.WatchPs(ctx, 0)
+	select {
+	case ev := <-eventChan:
+		if ev.Type != ModelLoaded {
+			t.Errorf("Expected %s, got %s", ModelLoaded, ev.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+
+	cancel()
+	for range eventChan {
+	}
+}